@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/conductorone/baton-coupa/pkg/connector"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// adminConnector is the part of the connector the admin commands run, so that
+// the commands can be tested without Coupa.
+type adminConnector interface {
+	OffboardUser(ctx context.Context, userId int, successorId int) (*connector.OffboardingReport, error)
+	MirrorAccess(ctx context.Context, sourceUserId int, targetUserId int, dryRun bool) (*connector.MirrorAccessReport, error)
+	MigrateRoleMembers(ctx context.Context, fromRoleId int, toRoleId int, move bool, checkpoint string) (*connector.RoleMigrationReport, error)
+	DeactivatePcard(ctx context.Context, pcardId int) (*connector.PcardDeactivationReport, error)
+	ListUserDocuments(ctx context.Context, userId int) (*connector.UserDocumentsReport, error)
+}
+
+// connectFunc creates the connector from the configuration for the command.
+// writes tells whether the command writes to Coupa, which needs provisioning
+// to be enabled.
+type connectFunc func(cmd *cobra.Command, writes bool) (adminConnector, error)
+
+// newConnectFunc binds the flags of the command to the configuration before
+// creating the connector, so the connector flags can be given to the admin
+// commands. Provisioning is taken from the configuration and never forced.
+func newConnectFunc(ctx context.Context, v *viper.Viper) connectFunc {
+	return func(cmd *cobra.Command, writes bool) (adminConnector, error) {
+		err := v.BindPFlags(cmd.Flags())
+		if err != nil {
+			return nil, err
+		}
+
+		provisioning := v.GetBool("provisioning")
+		if writes && !provisioning {
+			return nil, fmt.Errorf("baton-coupa: %s writes to Coupa, provisioning must be enabled", cmd.Name())
+		}
+		return newConnector(ctx, v, provisioning)
+	}
+}
+
+// withConnectorFlags adds the connector flags of the main command to an admin
+// command. The flags are shared, so values set from the environment or the
+// configuration file on the main command are seen by the admin command.
+func withConnectorFlags(mainCmd *cobra.Command, adminCmd *cobra.Command) *cobra.Command {
+	mainCmd.LocalNonPersistentFlags().VisitAll(func(f *pflag.Flag) {
+		if adminCmd.Flags().Lookup(f.Name) == nil {
+			adminCmd.Flags().AddFlag(f)
+		}
+	})
+	return adminCmd
+}
+
+// printReport writes the report of an admin command as JSON.
+func printReport(cmd *cobra.Command, report interface{}) error {
+	encoder := json.NewEncoder(cmd.OutOrStdout())
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/conductorone/baton-coupa/pkg/connector"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeConnector records the admin operations it is asked to run.
+type fakeConnector struct {
+	calls     []string
+	offboard  *connector.OffboardingReport
+	mirror    *connector.MirrorAccessReport
+	migration *connector.RoleMigrationReport
+	pcard     *connector.PcardDeactivationReport
+	documents *connector.UserDocumentsReport
+	err       error
+}
+
+func (f *fakeConnector) OffboardUser(ctx context.Context, userId int, successorId int) (*connector.OffboardingReport, error) {
+	f.calls = append(f.calls, "offboard")
+	return f.offboard, f.err
+}
+
+func (f *fakeConnector) MirrorAccess(ctx context.Context, sourceUserId int, targetUserId int, dryRun bool) (*connector.MirrorAccessReport, error) {
+	f.calls = append(f.calls, "mirror_access")
+	return f.mirror, f.err
+}
+
+func (f *fakeConnector) MigrateRoleMembers(ctx context.Context, fromRoleId int, toRoleId int, move bool, checkpoint string) (*connector.RoleMigrationReport, error) {
+	f.calls = append(f.calls, fmt.Sprintf("migrate_role_members %d %d %t %s", fromRoleId, toRoleId, move, checkpoint))
+	return f.migration, f.err
+}
+
+func (f *fakeConnector) DeactivatePcard(ctx context.Context, pcardId int) (*connector.PcardDeactivationReport, error) {
+	f.calls = append(f.calls, fmt.Sprintf("deactivate_pcard %d", pcardId))
+	return f.pcard, f.err
+}
+
+func (f *fakeConnector) ListUserDocuments(ctx context.Context, userId int) (*connector.UserDocumentsReport, error) {
+	f.calls = append(f.calls, fmt.Sprintf("list_documents %d", userId))
+	return f.documents, f.err
+}
+
+// runCommand runs the command with the fake connector, and returns its output
+// and whether the command writes to Coupa.
+func runCommand(
+	newCommand func(ctx context.Context, connect connectFunc) *cobra.Command,
+	fake *fakeConnector,
+	args ...string,
+) (string, bool, error) {
+	writes := false
+	cmd := newCommand(context.Background(), func(_ *cobra.Command, w bool) (adminConnector, error) {
+		writes = w
+		return fake, nil
+	})
+
+	output := &bytes.Buffer{}
+	cmd.SetOut(output)
+	cmd.SetErr(output)
+	cmd.SetArgs(args)
+	err := cmd.Execute()
+	return output.String(), writes, err
+}
+
+func TestWithConnectorFlags(t *testing.T) {
+	mainCmd := &cobra.Command{Use: "baton-coupa"}
+	mainCmd.Flags().String("coupa-domain", "", "")
+	mainCmd.PersistentFlags().Bool("provisioning", false, "")
+
+	adminCmd := withConnectorFlags(mainCmd, &cobra.Command{Use: "offboard"})
+	require.NotNil(t, adminCmd.Flags().Lookup("coupa-domain"))
+
+	// Values from the environment are set on the main command, and seen by
+	// the admin command.
+	require.NoError(t, mainCmd.Flags().Set("coupa-domain", "acme"))
+	domain, err := adminCmd.Flags().GetString("coupa-domain")
+	require.NoError(t, err)
+	require.Equal(t, "acme", domain)
+}
+
+func TestConnectFuncProvisioning(t *testing.T) {
+	v := viper.New()
+	cmd := &cobra.Command{Use: "offboard"}
+	cmd.Flags().Bool("provisioning", false, "")
+
+	connect := newConnectFunc(context.Background(), v)
+	_, err := connect(cmd, true)
+	require.ErrorContains(t, err, "provisioning must be enabled")
+}
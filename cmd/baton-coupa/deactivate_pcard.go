@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+)
+
+// deactivatePcardCommand deactivates a p-card and prints the card it
+// deactivated.
+func deactivatePcardCommand(ctx context.Context, connect connectFunc) *cobra.Command {
+	deactivateCmd := &cobra.Command{
+		Use:   "deactivate-pcard",
+		Short: "Deactivate a Coupa p-card, such as the card of an offboarded user",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			pcardId, err := cmd.Flags().GetInt("pcard-id")
+			if err != nil {
+				return err
+			}
+
+			c, err := connect(cmd, true)
+			if err != nil {
+				return err
+			}
+
+			report, err := c.DeactivatePcard(ctx, pcardId)
+			if report != nil {
+				printErr := printReport(cmd, report)
+				if printErr != nil {
+					return printErr
+				}
+			}
+			return err
+		},
+	}
+	deactivateCmd.Flags().Int("pcard-id", 0, "The ID of the Coupa p-card to deactivate")
+	_ = deactivateCmd.MarkFlagRequired("pcard-id")
+
+	return deactivateCmd
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/conductorone/baton-coupa/pkg/connector"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeactivatePcardCommand(t *testing.T) {
+	fake := &fakeConnector{pcard: &connector.PcardDeactivationReport{
+		PcardId:     3,
+		Card:        "****4242",
+		HolderId:    7,
+		Deactivated: true,
+	}}
+
+	output, writes, err := runCommand(deactivatePcardCommand, fake, "--pcard-id", "3")
+	require.NoError(t, err)
+	require.True(t, writes)
+	require.Equal(t, []string{"deactivate_pcard 3"}, fake.calls)
+	require.Contains(t, output, `"deactivated": true`)
+
+	fake = &fakeConnector{}
+	_, _, err = runCommand(deactivatePcardCommand, fake)
+	require.Error(t, err)
+	require.Empty(t, fake.calls)
+}
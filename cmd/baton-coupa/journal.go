@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	coppaConfig "github.com/conductorone/baton-coupa/pkg/config"
+	"github.com/conductorone/baton-coupa/pkg/connector/client"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// revertRequest is a request that puts back the before state of a journaled
+// write.
+type revertRequest struct {
+	Entry  int             `json:"entry"`
+	Hash   string          `json:"hash"`
+	Method string          `json:"method"`
+	Path   string          `json:"path"`
+	Body   json.RawMessage `json:"body"`
+}
+
+// journalCommand verifies the journal of the writes made to Coupa, and
+// replays their before states to revert them.
+func journalCommand(ctx context.Context, v *viper.Viper) *cobra.Command {
+	journalCmd := &cobra.Command{
+		Use:   "journal",
+		Short: "Verify and replay the journal of the writes made to Coupa",
+	}
+	journalCmd.PersistentFlags().String("path", "", "The path of the journal, defaults to coupa-journal-path")
+
+	verifyCmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Verify that the journal was not tampered with",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entries, err := readVerifiedJournal(cmd, v)
+			if err != nil {
+				return err
+			}
+
+			// A head recorded elsewhere, such as the one logged with each
+			// write, detects a journal rolled back along with its head.
+			headEntries, err := cmd.Flags().GetInt("head-entries")
+			if err != nil {
+				return err
+			}
+			headHash, err := cmd.Flags().GetString("head")
+			if err != nil {
+				return err
+			}
+			if headHash != "" {
+				if headEntries < 1 || headEntries > len(entries) {
+					return fmt.Errorf("baton-coupa: the journal has %d entries, the recorded head %d", len(entries), headEntries)
+				}
+				if entries[headEntries-1].Hash != headHash {
+					return fmt.Errorf("baton-coupa: journal entry %d is not the recorded head", headEntries-1)
+				}
+			}
+
+			head := ""
+			if len(entries) > 0 {
+				head = entries[len(entries)-1].Hash
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "journal ok: %d entries, head %s\n", len(entries), head)
+			return nil
+		},
+	}
+	verifyCmd.Flags().String("head", "", "A head hash recorded outside of the journal, such as the journal_head of a log")
+	verifyCmd.Flags().Int("head-entries", 0, "The number of entries of the journal when the head was recorded")
+	verifyCmd.MarkFlagsRequiredTogether("head", "head-entries")
+
+	replayCmd := &cobra.Command{
+		Use:   "replay",
+		Short: "Print the requests that revert the journaled writes, newest first",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entries, err := readVerifiedJournal(cmd, v)
+			if err != nil {
+				return err
+			}
+
+			from, err := cmd.Flags().GetInt("from")
+			if err != nil {
+				return err
+			}
+			if from < 0 || from > len(entries) {
+				return fmt.Errorf("baton-coupa: the journal has %d entries", len(entries))
+			}
+
+			apply, err := cmd.Flags().GetBool("apply")
+			if err != nil {
+				return err
+			}
+
+			var coupaClient *client.Client
+			if apply {
+				coupaClient, err = client.New(
+					ctx,
+					v.GetString(coppaConfig.CoupaDomain.FieldName),
+					v.GetString(coppaConfig.ClientIdField.FieldName),
+					v.GetString(coppaConfig.ClientSecretField.FieldName),
+					v.GetString(coppaConfig.WriteClientIdField.FieldName),
+					v.GetString(coppaConfig.WriteClientSecretField.FieldName),
+					nil,
+					true,
+					false,
+					journalPath(cmd, v),
+					v.GetString(coppaConfig.JournalKeyField.FieldName),
+				)
+				if err != nil {
+					return err
+				}
+			}
+
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			for i := len(entries) - 1; i >= from; i-- {
+				entry := entries[i]
+				if !entry.Revertible() {
+					fmt.Fprintf(cmd.ErrOrStderr(), "skipping entry %d: the %s %s write cannot be reverted\n", i, entry.Method, entry.Path)
+					continue
+				}
+
+				err = encoder.Encode(revertRequest{
+					Entry:  i,
+					Hash:   entry.Hash,
+					Method: entry.Method,
+					Path:   entry.Path,
+					Body:   entry.Before,
+				})
+				if err != nil {
+					return err
+				}
+
+				if apply {
+					err = coupaClient.Revert(ctx, entry)
+					if err != nil {
+						return fmt.Errorf("baton-coupa: reverting entry %d: %w", i, err)
+					}
+				}
+			}
+			return nil
+		},
+	}
+	replayCmd.Flags().Int("from", 0, "The index of the oldest entry to revert")
+	replayCmd.Flags().Bool("apply", false, "Send the requests to Coupa, with the credentials of the configuration")
+
+	journalCmd.AddCommand(verifyCmd, replayCmd)
+	return journalCmd
+}
+
+func journalPath(cmd *cobra.Command, v *viper.Viper) string {
+	path, err := cmd.Flags().GetString("path")
+	if err != nil || path == "" {
+		return v.GetString(coppaConfig.JournalPathField.FieldName)
+	}
+	return path
+}
+
+func readVerifiedJournal(cmd *cobra.Command, v *viper.Viper) ([]client.JournalEntry, error) {
+	path := journalPath(cmd, v)
+	if path == "" {
+		return nil, fmt.Errorf("baton-coupa: no journal path, set --path or coupa-journal-path")
+	}
+
+	key := v.GetString(coppaConfig.JournalKeyField.FieldName)
+	if key == "" {
+		return nil, fmt.Errorf("baton-coupa: no journal key, set coupa-journal-key")
+	}
+
+	entries, err := client.ReadJournal(path)
+	if err != nil {
+		return nil, err
+	}
+
+	head, err := client.ReadJournalHead(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	_, err = client.VerifyJournal(entries, head, key)
+	if err != nil {
+		fmt.Fprintln(cmd.ErrOrStderr(), "the journal was tampered with, entries from there on cannot be trusted")
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+)
+
+// listDocumentsCommand prints the pending approvals and open documents
+// assigned to a user, to review them before offboarding the user.
+func listDocumentsCommand(ctx context.Context, connect connectFunc) *cobra.Command {
+	listCmd := &cobra.Command{
+		Use:   "list-documents",
+		Short: "List the pending approvals and open documents assigned to a Coupa user",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			userId, err := cmd.Flags().GetInt("user-id")
+			if err != nil {
+				return err
+			}
+
+			c, err := connect(cmd, false)
+			if err != nil {
+				return err
+			}
+
+			report, err := c.ListUserDocuments(ctx, userId)
+			if err != nil {
+				return err
+			}
+			return printReport(cmd, report)
+		},
+	}
+	listCmd.Flags().Int("user-id", 0, "The ID of the Coupa user whose documents are listed")
+	_ = listCmd.MarkFlagRequired("user-id")
+
+	return listCmd
+}
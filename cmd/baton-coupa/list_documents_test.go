@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/conductorone/baton-coupa/pkg/connector"
+	"github.com/conductorone/baton-coupa/pkg/connector/client"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListDocumentsCommand(t *testing.T) {
+	fake := &fakeConnector{documents: &connector.UserDocumentsReport{
+		UserId: 7,
+		Documents: map[client.DocumentType][]int{
+			client.DocumentTypePendingApproval: {11, 12},
+			client.DocumentTypeRequisition:     {},
+		},
+	}}
+
+	output, writes, err := runCommand(listDocumentsCommand, fake, "--user-id", "7")
+	require.NoError(t, err)
+	// Listing the documents only reads from Coupa.
+	require.False(t, writes)
+	require.Equal(t, []string{"list_documents 7"}, fake.calls)
+	require.Contains(t, output, `"pending_approvals": [`)
+
+	fake = &fakeConnector{}
+	_, _, err = runCommand(listDocumentsCommand, fake)
+	require.Error(t, err)
+	require.Empty(t, fake.calls)
+}
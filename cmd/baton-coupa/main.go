@@ -23,7 +23,7 @@ var (
 func main() {
 	ctx := context.Background()
 
-	_, cmd, err := config.DefineConfiguration(
+	v, cmd, err := config.DefineConfiguration(
 		ctx,
 		connectorName,
 		getConnector,
@@ -35,6 +35,13 @@ func main() {
 	}
 
 	cmd.Version = version
+	cmd.AddCommand(journalCommand(ctx, v))
+	connect := newConnectFunc(ctx, v)
+	cmd.AddCommand(withConnectorFlags(cmd, offboardCommand(ctx, connect)))
+	cmd.AddCommand(withConnectorFlags(cmd, mirrorAccessCommand(ctx, connect)))
+	cmd.AddCommand(withConnectorFlags(cmd, migrateRoleMembersCommand(ctx, connect)))
+	cmd.AddCommand(withConnectorFlags(cmd, deactivatePcardCommand(ctx, connect)))
+	cmd.AddCommand(withConnectorFlags(cmd, listDocumentsCommand(ctx, connect)))
 
 	err = cmd.Execute()
 	if err != nil {
@@ -45,16 +52,8 @@ func main() {
 
 func getConnector(ctx context.Context, v *viper.Viper) (types.ConnectorServer, error) {
 	l := ctxzap.Extract(ctx)
-	if err := coppaConfig.ValidateConfig(v); err != nil {
-		return nil, err
-	}
 
-	cb, err := connector.New(
-		ctx,
-		v.GetString(coppaConfig.CoupaDomain.FieldName),
-		v.GetString(coppaConfig.ClientIdField.FieldName),
-		v.GetString(coppaConfig.ClientSecretField.FieldName),
-	)
+	cb, err := newConnector(ctx, v, v.GetBool("provisioning"))
 	if err != nil {
 		l.Error("error creating connector", zap.Error(err))
 		return nil, err
@@ -66,3 +65,47 @@ func getConnector(ctx context.Context, v *viper.Viper) (types.ConnectorServer, e
 	}
 	return connector, nil
 }
+
+func newConnector(ctx context.Context, v *viper.Viper, provisioning bool) (*connector.Connector, error) {
+	if err := coppaConfig.ValidateConfig(v); err != nil {
+		return nil, err
+	}
+
+	return connector.New(ctx, connector.Config{
+		InstanceUrl:         v.GetString(coppaConfig.CoupaDomain.FieldName),
+		ClientId:            v.GetString(coppaConfig.ClientIdField.FieldName),
+		ClientSecret:        v.GetString(coppaConfig.ClientSecretField.FieldName),
+		WriteClientId:       v.GetString(coppaConfig.WriteClientIdField.FieldName),
+		WriteClientSecret:   v.GetString(coppaConfig.WriteClientSecretField.FieldName),
+		BudgetPeriod:        v.GetString(coppaConfig.BudgetPeriodField.FieldName),
+		SyncOffboardingRisk: v.GetBool(coppaConfig.SyncOffboardingRiskField.FieldName),
+		Provisioning:        provisioning,
+		DryRun:              v.GetBool(coppaConfig.DryRunField.FieldName),
+		JournalPath:         v.GetString(coppaConfig.JournalPathField.FieldName),
+		JournalKey:          v.GetString(coppaConfig.JournalKeyField.FieldName),
+		ResourceTypes:       v.GetStringSlice(coppaConfig.ResourceTypesField.FieldName),
+		Scopes:              v.GetStringSlice(coppaConfig.ScopesField.FieldName),
+
+		AllowedRoles:           v.GetStringSlice(coppaConfig.AllowedRolesField.FieldName),
+		DeniedRoles:            v.GetStringSlice(coppaConfig.DeniedRolesField.FieldName),
+		AllowedGroups:          v.GetStringSlice(coppaConfig.AllowedGroupsField.FieldName),
+		DeniedGroups:           v.GetStringSlice(coppaConfig.DeniedGroupsField.FieldName),
+		AllowedLicenses:        v.GetStringSlice(coppaConfig.AllowedLicensesField.FieldName),
+		DeniedLicenses:         v.GetStringSlice(coppaConfig.DeniedLicensesField.FieldName),
+		AllowedApprovalGroups:  v.GetStringSlice(coppaConfig.AllowedApprovalGroupsField.FieldName),
+		DeniedApprovalGroups:   v.GetStringSlice(coppaConfig.DeniedApprovalGroupsField.FieldName),
+		AllowedApprovalLimits:  v.GetStringSlice(coppaConfig.AllowedApprovalLimitsField.FieldName),
+		DeniedApprovalLimits:   v.GetStringSlice(coppaConfig.DeniedApprovalLimitsField.FieldName),
+		AllowedWarehouses:      v.GetStringSlice(coppaConfig.AllowedWarehousesField.FieldName),
+		DeniedWarehouses:       v.GetStringSlice(coppaConfig.DeniedWarehousesField.FieldName),
+		AllowedDepartments:     v.GetStringSlice(coppaConfig.AllowedDepartmentsField.FieldName),
+		DeniedDepartments:      v.GetStringSlice(coppaConfig.DeniedDepartmentsField.FieldName),
+		ProtectedUsers:         v.GetStringSlice(coppaConfig.ProtectedUsersField.FieldName),
+		CriticalRoles:          v.GetStringSlice(coppaConfig.CriticalRolesField.FieldName),
+		MinCriticalRoleHolders: v.GetInt(coppaConfig.MinCriticalRoleHoldersField.FieldName),
+		SodRulesPath:           v.GetString(coppaConfig.SodRulesPathField.FieldName),
+
+		PrivilegedRoles:   v.GetStringSlice(coppaConfig.PrivilegedRolesField.FieldName),
+		UnprivilegedRoles: v.GetStringSlice(coppaConfig.UnprivilegedRolesField.FieldName),
+	})
+}
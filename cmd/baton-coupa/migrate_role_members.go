@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+)
+
+// migrateRoleMembersCommand copies or moves the members of a role to another
+// role and prints the result for each member. When interrupted, the printed
+// checkpoint resumes the migration.
+func migrateRoleMembersCommand(ctx context.Context, connect connectFunc) *cobra.Command {
+	migrateCmd := &cobra.Command{
+		Use:   "migrate-role-members",
+		Short: "Copy or move the members of a Coupa role to another role",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fromRoleId, err := cmd.Flags().GetInt("from-role-id")
+			if err != nil {
+				return err
+			}
+			toRoleId, err := cmd.Flags().GetInt("to-role-id")
+			if err != nil {
+				return err
+			}
+			move, err := cmd.Flags().GetBool("move")
+			if err != nil {
+				return err
+			}
+			checkpoint, err := cmd.Flags().GetString("checkpoint")
+			if err != nil {
+				return err
+			}
+
+			c, err := connect(cmd, true)
+			if err != nil {
+				return err
+			}
+
+			report, err := c.MigrateRoleMembers(ctx, fromRoleId, toRoleId, move, checkpoint)
+			if report != nil {
+				printErr := printReport(cmd, report)
+				if printErr != nil {
+					return printErr
+				}
+			}
+			return err
+		},
+	}
+	migrateCmd.Flags().Int("from-role-id", 0, "The ID of the Coupa role whose members are migrated")
+	migrateCmd.Flags().Int("to-role-id", 0, "The ID of the Coupa role the members are given")
+	migrateCmd.Flags().Bool("move", false, "Remove the members from the original role")
+	migrateCmd.Flags().String("checkpoint", "", "Resume an interrupted migration after this user ID")
+	_ = migrateCmd.MarkFlagRequired("from-role-id")
+	_ = migrateCmd.MarkFlagRequired("to-role-id")
+
+	return migrateCmd
+}
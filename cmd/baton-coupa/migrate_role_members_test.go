@@ -0,0 +1,35 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/conductorone/baton-coupa/pkg/connector"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateRoleMembersCommand(t *testing.T) {
+	fake := &fakeConnector{
+		migration: &connector.RoleMigrationReport{
+			FromRoleId: 1,
+			ToRoleId:   2,
+			Move:       true,
+			Checkpoint: "42",
+		},
+		err: errors.New("context canceled"),
+	}
+
+	output, writes, err := runCommand(
+		migrateRoleMembersCommand,
+		fake,
+		"--from-role-id", "1",
+		"--to-role-id", "2",
+		"--move",
+		"--checkpoint", "40",
+	)
+	require.Error(t, err)
+	require.True(t, writes)
+	require.Equal(t, []string{"migrate_role_members 1 2 true 40"}, fake.calls)
+	// The checkpoint reached is printed so the migration can be resumed.
+	require.Contains(t, output, `"checkpoint": "42"`)
+}
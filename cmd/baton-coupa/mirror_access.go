@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+)
+
+// mirrorAccessCommand gives a user the access of another user and prints the
+// access it got.
+func mirrorAccessCommand(ctx context.Context, connect connectFunc) *cobra.Command {
+	mirrorCmd := &cobra.Command{
+		Use:   "mirror-access",
+		Short: "Give a Coupa user the roles, groups and licenses of another user",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			sourceUserId, err := cmd.Flags().GetInt("source-user-id")
+			if err != nil {
+				return err
+			}
+			targetUserId, err := cmd.Flags().GetInt("target-user-id")
+			if err != nil {
+				return err
+			}
+			dryRun, err := cmd.Flags().GetBool("dry-run")
+			if err != nil {
+				return err
+			}
+
+			c, err := connect(cmd, !dryRun)
+			if err != nil {
+				return err
+			}
+
+			report, err := c.MirrorAccess(ctx, sourceUserId, targetUserId, dryRun)
+			if report != nil {
+				printErr := printReport(cmd, report)
+				if printErr != nil {
+					return printErr
+				}
+			}
+			return err
+		},
+	}
+	mirrorCmd.Flags().Int("source-user-id", 0, "The ID of the Coupa user whose access is mirrored")
+	mirrorCmd.Flags().Int("target-user-id", 0, "The ID of the Coupa user that gets the access")
+	mirrorCmd.Flags().Bool("dry-run", false, "Only print the access the target user would get")
+	_ = mirrorCmd.MarkFlagRequired("source-user-id")
+	_ = mirrorCmd.MarkFlagRequired("target-user-id")
+
+	return mirrorCmd
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/conductorone/baton-coupa/pkg/connector"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMirrorAccessCommand(t *testing.T) {
+	fake := &fakeConnector{mirror: &connector.MirrorAccessReport{
+		SourceUserId: 7,
+		TargetUserId: 9,
+		DryRun:       true,
+		Changes:      []connector.AccessChange{{ResourceType: "role", Id: "2", Name: "Buyer"}},
+	}}
+
+	output, writes, err := runCommand(mirrorAccessCommand, fake, "--source-user-id", "7", "--target-user-id", "9", "--dry-run")
+	require.NoError(t, err)
+	// A dry run only reads from Coupa.
+	require.False(t, writes)
+	require.Equal(t, []string{"mirror_access"}, fake.calls)
+	require.Contains(t, output, `"name": "Buyer"`)
+
+	_, writes, err = runCommand(mirrorAccessCommand, fake, "--source-user-id", "7", "--target-user-id", "9")
+	require.NoError(t, err)
+	require.True(t, writes)
+
+	_, _, err = runCommand(mirrorAccessCommand, &fakeConnector{}, "--source-user-id", "7")
+	require.Error(t, err)
+}
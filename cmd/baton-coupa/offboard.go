@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+)
+
+// offboardCommand offboards a user and prints the report of each step. It
+// fails when any step failed, and can be run again once the cause is fixed.
+func offboardCommand(ctx context.Context, connect connectFunc) *cobra.Command {
+	offboardCmd := &cobra.Command{
+		Use:   "offboard",
+		Short: "Offboard a Coupa user, with the credentials of the configuration",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			userId, err := cmd.Flags().GetInt("user-id")
+			if err != nil {
+				return err
+			}
+			successorId, err := cmd.Flags().GetInt("successor-id")
+			if err != nil {
+				return err
+			}
+
+			c, err := connect(cmd, true)
+			if err != nil {
+				return err
+			}
+
+			report, err := c.OffboardUser(ctx, userId, successorId)
+			if report != nil {
+				printErr := printReport(cmd, report)
+				if printErr != nil {
+					return printErr
+				}
+			}
+			return err
+		},
+	}
+	offboardCmd.Flags().Int("user-id", 0, "The ID of the Coupa user to offboard")
+	offboardCmd.Flags().Int("successor-id", 0, "The ID of the Coupa user the pending approvals and open documents are reassigned to")
+	_ = offboardCmd.MarkFlagRequired("user-id")
+
+	return offboardCmd
+}
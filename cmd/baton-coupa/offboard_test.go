@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/conductorone/baton-coupa/pkg/connector"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOffboardCommand(t *testing.T) {
+	t.Run("done", func(t *testing.T) {
+		fake := &fakeConnector{offboard: &connector.OffboardingReport{
+			UserId:      7,
+			SuccessorId: 9,
+			Steps:       []connector.OffboardingStep{{Name: "deactivate", Status: connector.OffboardingStepDone}},
+		}}
+		output, writes, err := runCommand(offboardCommand, fake, "--user-id", "7", "--successor-id", "9")
+		require.NoError(t, err)
+		require.True(t, writes)
+		require.Equal(t, []string{"offboard"}, fake.calls)
+		require.Contains(t, output, `"name": "deactivate"`)
+	})
+
+	t.Run("failed step", func(t *testing.T) {
+		fake := &fakeConnector{
+			offboard: &connector.OffboardingReport{
+				UserId: 7,
+				Steps:  []connector.OffboardingStep{{Name: "remove_roles", Status: connector.OffboardingStepFailed}},
+			},
+			err: errors.New("baton-coupa: offboarding did not complete"),
+		}
+		output, _, err := runCommand(offboardCommand, fake, "--user-id", "7")
+		require.Error(t, err)
+		require.Contains(t, output, `"status": "failed"`)
+	})
+
+	t.Run("missing user", func(t *testing.T) {
+		fake := &fakeConnector{}
+		_, _, err := runCommand(offboardCommand, fake)
+		require.Error(t, err)
+		require.Empty(t, fake.calls)
+	})
+}
@@ -32,6 +32,125 @@ var (
 		field.WithRequired(true),
 		field.WithDescription("Your Coupa Domain, ex: acme.coupacloud.com"),
 	)
+	BudgetPeriodField = field.StringField(
+		"coupa-budget-period",
+		field.WithDescription("Only sync the budget lines of this Coupa budget period, ex: FY2025"),
+	)
+	SyncOffboardingRiskField = field.BoolField(
+		"coupa-sync-offboarding-risk",
+		field.WithDescription("Add the counts of pending approvals and open documents assigned to each user to their profile"),
+	)
+	ResourceTypesField = field.StringSliceField(
+		"coupa-resource-types",
+		field.WithDescription("The resource types to sync, defaults to all of them"),
+	)
+	ScopesField = field.StringSliceField(
+		"coupa-scopes",
+		field.WithDescription("The OAuth scopes requested for syncing, defaults to the scopes needed by the synced resource types"),
+	)
+	AllowedRolesField = field.StringSliceField(
+		"coupa-allowed-role-ids",
+		field.WithDescription("Only these Coupa role IDs can be granted"),
+	)
+	DeniedRolesField = field.StringSliceField(
+		"coupa-denied-role-ids",
+		field.WithDescription("These Coupa role IDs can never be granted"),
+	)
+	AllowedGroupsField = field.StringSliceField(
+		"coupa-allowed-group-ids",
+		field.WithDescription("Only these Coupa user group IDs can be granted"),
+	)
+	DeniedGroupsField = field.StringSliceField(
+		"coupa-denied-group-ids",
+		field.WithDescription("These Coupa user group IDs can never be granted"),
+	)
+	AllowedLicensesField = field.StringSliceField(
+		"coupa-allowed-license-ids",
+		field.WithDescription("Only these Coupa license IDs can be granted, ex: expense-user"),
+	)
+	DeniedLicensesField = field.StringSliceField(
+		"coupa-denied-license-ids",
+		field.WithDescription("These Coupa license IDs can never be granted, ex: expense-user"),
+	)
+	AllowedApprovalGroupsField = field.StringSliceField(
+		"coupa-allowed-approval-group-ids",
+		field.WithDescription("Only these Coupa approval group IDs can be granted"),
+	)
+	DeniedApprovalGroupsField = field.StringSliceField(
+		"coupa-denied-approval-group-ids",
+		field.WithDescription("These Coupa approval group IDs can never be granted"),
+	)
+	AllowedApprovalLimitsField = field.StringSliceField(
+		"coupa-allowed-approval-limit-ids",
+		field.WithDescription("Only these Coupa approval limit IDs can be granted"),
+	)
+	DeniedApprovalLimitsField = field.StringSliceField(
+		"coupa-denied-approval-limit-ids",
+		field.WithDescription("These Coupa approval limit IDs can never be granted"),
+	)
+	AllowedWarehousesField = field.StringSliceField(
+		"coupa-allowed-warehouse-ids",
+		field.WithDescription("Only these Coupa warehouse IDs can be granted"),
+	)
+	DeniedWarehousesField = field.StringSliceField(
+		"coupa-denied-warehouse-ids",
+		field.WithDescription("These Coupa warehouse IDs can never be granted"),
+	)
+	AllowedDepartmentsField = field.StringSliceField(
+		"coupa-allowed-department-ids",
+		field.WithDescription("Only these Coupa department IDs can be granted"),
+	)
+	DeniedDepartmentsField = field.StringSliceField(
+		"coupa-denied-department-ids",
+		field.WithDescription("These Coupa department IDs can never be granted"),
+	)
+	ProtectedUsersField = field.StringSliceField(
+		"coupa-protected-user-ids",
+		field.WithDescription("The Coupa user IDs whose access is never changed, ex: integration service accounts"),
+	)
+	CriticalRolesField = field.StringSliceField(
+		"coupa-critical-roles",
+		field.WithDescription("The names of the Coupa roles that must keep a minimum of active holders"),
+		field.WithDefaultValue([]string{"Admin"}),
+	)
+	MinCriticalRoleHoldersField = field.IntField(
+		"coupa-critical-role-min-holders",
+		field.WithDescription("The minimum of active holders each critical role must keep"),
+		field.WithDefaultValue(1),
+	)
+	SodRulesPathField = field.StringField(
+		"coupa-sod-rules-path",
+		field.WithDescription("The path to a YAML file of separation of duties rules checked when granting and syncing"),
+	)
+	PrivilegedRolesField = field.StringSliceField(
+		"coupa-privileged-roles",
+		field.WithDescription("The names of the Coupa roles always classified as privileged"),
+		field.WithDefaultValue([]string{"Admin"}),
+	)
+	UnprivilegedRolesField = field.StringSliceField(
+		"coupa-unprivileged-roles",
+		field.WithDescription("The names of the Coupa roles never classified as privileged"),
+	)
+	DryRunField = field.BoolField(
+		"coupa-dry-run",
+		field.WithDescription("Log the provisioning requests instead of sending them to Coupa"),
+	)
+	JournalPathField = field.StringField(
+		"coupa-journal-path",
+		field.WithDescription("The path of a JSON-lines journal every provisioning request to Coupa is appended to"),
+	)
+	JournalKeyField = field.StringField(
+		"coupa-journal-key",
+		field.WithDescription("The secret key the entries of the journal are signed with, required with coupa-journal-path"),
+	)
+	WriteClientIdField = field.StringField(
+		"coupa-write-client-id",
+		field.WithDescription("The Coupa Client ID used for provisioning, defaults to coupa-client-id"),
+	)
+	WriteClientSecretField = field.StringField(
+		"coupa-write-client-secret",
+		field.WithDescription("The Coupa Client Secret used for provisioning, defaults to coupa-client-secret"),
+	)
 	// ConfigurationFields defines the external configuration required for the
 	// connector to run. Note: these fields can be marked as optional or
 	// required.
@@ -39,10 +158,43 @@ var (
 		ClientIdField,
 		ClientSecretField,
 		CoupaDomain,
+		BudgetPeriodField,
+		SyncOffboardingRiskField,
+		ResourceTypesField,
+		ScopesField,
+		AllowedRolesField,
+		DeniedRolesField,
+		AllowedGroupsField,
+		DeniedGroupsField,
+		AllowedLicensesField,
+		DeniedLicensesField,
+		AllowedApprovalGroupsField,
+		DeniedApprovalGroupsField,
+		AllowedApprovalLimitsField,
+		DeniedApprovalLimitsField,
+		AllowedWarehousesField,
+		DeniedWarehousesField,
+		AllowedDepartmentsField,
+		DeniedDepartmentsField,
+		ProtectedUsersField,
+		CriticalRolesField,
+		MinCriticalRoleHoldersField,
+		SodRulesPathField,
+		PrivilegedRolesField,
+		UnprivilegedRolesField,
+		DryRunField,
+		JournalPathField,
+		JournalKeyField,
+		WriteClientIdField,
+		WriteClientSecretField,
 	}
 
 	ConfigurationSchema = field.Configuration{
 		Fields: ConfigurationFields,
+		Constraints: []field.SchemaFieldRelationship{
+			field.FieldsRequiredTogether(WriteClientIdField, WriteClientSecretField),
+			field.FieldsDependentOn([]field.SchemaField{JournalPathField}, []field.SchemaField{JournalKeyField}),
+		},
 	}
 )
 
@@ -33,6 +33,48 @@ func TestConfigs(t *testing.T) {
 				"coupa-domain":        "https://example.coupacloud.com",
 			},
 		},
+		{
+			Message: "write client id without secret",
+			IsValid: false,
+			Configs: map[string]string{
+				"coupa-client-id":       "1",
+				"coupa-client-secret":   "1",
+				"coupa-domain":          "https://example.coupacloud.com",
+				"coupa-write-client-id": "2",
+			},
+		},
+		{
+			Message: "journal without key",
+			IsValid: false,
+			Configs: map[string]string{
+				"coupa-client-id":     "1",
+				"coupa-client-secret": "1",
+				"coupa-domain":        "https://example.coupacloud.com",
+				"coupa-journal-path":  "journal.jsonl",
+			},
+		},
+		{
+			Message: "journal",
+			IsValid: true,
+			Configs: map[string]string{
+				"coupa-client-id":     "1",
+				"coupa-client-secret": "1",
+				"coupa-domain":        "https://example.coupacloud.com",
+				"coupa-journal-path":  "journal.jsonl",
+				"coupa-journal-key":   "secret",
+			},
+		},
+		{
+			Message: "write credentials",
+			IsValid: true,
+			Configs: map[string]string{
+				"coupa-client-id":           "1",
+				"coupa-client-secret":       "1",
+				"coupa-domain":              "https://example.coupacloud.com",
+				"coupa-write-client-id":     "2",
+				"coupa-write-client-secret": "2",
+			},
+		},
 	}
 
 	test.ExerciseTestCases(t, ConfigurationSchema, ValidateConfig, testCases)
@@ -0,0 +1,171 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/conductorone/baton-coupa/pkg/connector/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	resourceSdk "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+type apiKeyBuilder struct {
+	client *client.Client
+}
+
+func (o *apiKeyBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return apiKeyResourceType
+}
+
+const apiKeyPermissionsProfileField = "permissions"
+
+// Create a new connector resource for a legacy Coupa API key. The
+// permissions of the key are kept in the profile, for Grants to read back.
+func apiKeyResource(apiKey *client.ApiKey, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	status := v2.UserTrait_Status_STATUS_DISABLED
+	if apiKey.Active {
+		status = v2.UserTrait_Status_STATUS_ENABLED
+	}
+
+	name := apiKey.Name
+	if name == "" {
+		name = fmt.Sprintf("API key %d", apiKey.ID)
+	}
+
+	permissions := make([]interface{}, 0, len(apiKey.Permissions))
+	for _, permission := range apiKey.Permissions {
+		permissions = append(permissions, permission)
+	}
+
+	profile := map[string]interface{}{
+		"id":     apiKey.ID,
+		"name":   apiKey.Name,
+		"active": apiKey.Active,
+
+		apiKeyPermissionsProfileField: permissions,
+	}
+
+	options := []resourceSdk.UserTraitOption{
+		resourceSdk.WithStatus(status),
+		resourceSdk.WithAccountType(v2.UserTrait_ACCOUNT_TYPE_SERVICE),
+	}
+
+	if apiKey.CreatedBy != nil {
+		profile["created_by_id"] = apiKey.CreatedBy.Id
+		profile["created_by"] = apiKey.CreatedBy.Login
+	}
+
+	if apiKey.CreatedAt != nil {
+		profile["created_at"] = *apiKey.CreatedAt
+		createdAt, err := time.Parse(time.RFC3339, *apiKey.CreatedAt)
+		if err == nil {
+			options = append(options, resourceSdk.WithCreatedAt(createdAt))
+		}
+	}
+
+	if apiKey.LastUsedAt != nil {
+		profile["last_used_at"] = *apiKey.LastUsedAt
+		lastUsedAt, err := time.Parse(time.RFC3339, *apiKey.LastUsedAt)
+		if err == nil {
+			options = append(options, resourceSdk.WithLastLogin(lastUsedAt))
+		}
+	}
+
+	options = append(options, resourceSdk.WithUserProfile(profile))
+
+	return resourceSdk.NewUserResource(
+		name,
+		apiKeyResourceType,
+		apiKey.ID,
+		options,
+		resourceSdk.WithParentResourceID(parentResourceID),
+		resourceSdk.WithDescription("Legacy API key in Coupa"),
+	)
+}
+
+func (o *apiKeyBuilder) List(
+	ctx context.Context,
+	parentResourceID *v2.ResourceId,
+	pToken *pagination.Token,
+) (
+	[]*v2.Resource,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	logger := ctxzap.Extract(ctx)
+	logger.Debug("Starting API Keys List", zap.String("token", pToken.Token))
+
+	outputResources := make([]*v2.Resource, 0)
+	var outputAnnotations annotations.Annotations
+
+	var target client.ApiKeysQueryResponse
+	response, ratelimitData, err := o.client.Query(
+		ctx,
+		client.ApiKeysQuery(pToken.Token),
+		&target,
+	)
+	outputAnnotations.WithRateLimiting(ratelimitData)
+	if err != nil {
+		return nil, "", outputAnnotations, err
+	}
+	defer response.Body.Close()
+
+	lastId := ""
+	for _, apiKey := range target.ApiKeys {
+		resource, err := apiKeyResource(apiKey, parentResourceID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		outputResources = append(outputResources, resource)
+		lastId = strconv.Itoa(apiKey.ID)
+	}
+
+	return outputResources, lastId, outputAnnotations, nil
+}
+
+// Entitlements always returns an empty slice for API keys.
+func (o *apiKeyBuilder) Entitlements(
+	_ context.Context,
+	_ *v2.Resource,
+	_ *pagination.Token,
+) (
+	[]*v2.Entitlement,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	return nil, "", nil, nil
+}
+
+// Grants returns a read-only grant of each API permission the key holds. The
+// permissions are read from the profile, so no request is made.
+func (o *apiKeyBuilder) Grants(
+	_ context.Context,
+	resource *v2.Resource,
+	_ *pagination.Token,
+) (
+	[]*v2.Grant,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	return heldPermissionGrants(
+		resource,
+		apiKeyPermissionsProfileField,
+		apiPermissionResourceType,
+		apiPermissionGrantedEntitlementName,
+	), "", nil, nil
+}
+
+func newApiKeyBuilder(ctx context.Context, client *client.Client) *apiKeyBuilder {
+	return &apiKeyBuilder{
+		client: client,
+	}
+}
@@ -0,0 +1,21 @@
+package connector
+
+import (
+	"testing"
+
+	"github.com/conductorone/baton-coupa/pkg/connector/client"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApiKeyGrants(t *testing.T) {
+	resource, err := apiKeyResource(&client.ApiKey{
+		ID:          5,
+		Permissions: []string{"users#index"},
+	}, nil)
+	require.NoError(t, err)
+
+	grants := heldPermissionGrants(resource, apiKeyPermissionsProfileField, apiPermissionResourceType, apiPermissionGrantedEntitlementName)
+	require.Len(t, grants, 1)
+	require.Equal(t, "api_permission:users#index:granted", grants[0].Entitlement.Id)
+	require.Equal(t, "5", grants[0].Principal.Id.Resource)
+}
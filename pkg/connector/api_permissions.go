@@ -0,0 +1,144 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/conductorone/baton-coupa/pkg/connector/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	resourceSdk "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+)
+
+const apiPermissionGrantedEntitlementName = "granted"
+
+// apiPermissionBuilder syncs the API resource permissions held by legacy API
+// keys. Permissions are not listed by Coupa, they are collected from the
+// keys.
+type apiPermissionBuilder struct {
+	client *client.Client
+}
+
+func (o *apiPermissionBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return apiPermissionResourceType
+}
+
+func apiPermissionResource(permission string, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	return resourceSdk.NewResource(
+		permission,
+		apiPermissionResourceType,
+		permission,
+		resourceSdk.WithParentResourceID(parentResourceID),
+		resourceSdk.WithDescription(fmt.Sprintf("%s API permission in Coupa", permission)),
+	)
+}
+
+// List returns every permission granted to at least one API key.
+func (o *apiPermissionBuilder) List(
+	ctx context.Context,
+	parentResourceID *v2.ResourceId,
+	_ *pagination.Token,
+) (
+	[]*v2.Resource,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	logger := ctxzap.Extract(ctx)
+	logger.Debug("Starting API Permissions List")
+
+	outputResources := make([]*v2.Resource, 0)
+	var outputAnnotations annotations.Annotations
+
+	permissions := make([]string, 0)
+	lastId := ""
+	for {
+		var target client.ApiKeysQueryResponse
+		response, ratelimitData, err := o.client.Query(
+			ctx,
+			client.ApiKeysQuery(lastId),
+			&target,
+		)
+		outputAnnotations.WithRateLimiting(ratelimitData)
+		if err != nil {
+			return nil, "", outputAnnotations, err
+		}
+		response.Body.Close()
+
+		if len(target.ApiKeys) == 0 {
+			break
+		}
+
+		for _, apiKey := range target.ApiKeys {
+			for _, permission := range apiKey.Permissions {
+				if !slices.Contains(permissions, permission) {
+					permissions = append(permissions, permission)
+				}
+			}
+			lastId = strconv.Itoa(apiKey.ID)
+		}
+	}
+
+	slices.Sort(permissions)
+	for _, permission := range permissions {
+		resource, err := apiPermissionResource(permission, parentResourceID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		outputResources = append(outputResources, resource)
+	}
+
+	return outputResources, "", outputAnnotations, nil
+}
+
+func (o *apiPermissionBuilder) Entitlements(
+	_ context.Context,
+	resource *v2.Resource,
+	_ *pagination.Token,
+) (
+	[]*v2.Entitlement,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	return []*v2.Entitlement{
+		entitlement.NewPermissionEntitlement(
+			resource,
+			apiPermissionGrantedEntitlementName,
+			entitlement.WithGrantableTo(apiKeyResourceType),
+			entitlement.WithDisplayName(
+				fmt.Sprintf("%s API Permission", resource.DisplayName),
+			),
+			entitlement.WithDescription(
+				fmt.Sprintf("%s API permission in Coupa", resource.DisplayName),
+			),
+			entitlement.WithAnnotation(&v2.EntitlementImmutable{}),
+		),
+	}, "", nil, nil
+}
+
+// Grants always returns an empty slice for API permissions, the grants are
+// returned by the API keys that hold them.
+func (o *apiPermissionBuilder) Grants(
+	_ context.Context,
+	_ *v2.Resource,
+	_ *pagination.Token,
+) (
+	[]*v2.Grant,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	return nil, "", nil, nil
+}
+
+func newApiPermissionBuilder(ctx context.Context, client *client.Client) *apiPermissionBuilder {
+	return &apiPermissionBuilder{
+		client: client,
+	}
+}
@@ -0,0 +1,210 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/conductorone/baton-coupa/pkg/connector/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	resourceSdk "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+const approvalChainApproverEntitlementName = "approver"
+
+// approvalChainBuilder syncs approval chains. Approvers are read-only, they
+// are managed from the approval chain setup in Coupa.
+type approvalChainBuilder struct {
+	client *client.Client
+}
+
+func (o *approvalChainBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return approvalChainResourceType
+}
+
+func approvalChainResource(approvalChain *client.ApprovalChain, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	description := fmt.Sprintf("%s approval chain in Coupa", approvalChain.Name)
+	if approvalChain.Description != nil && *approvalChain.Description != "" {
+		description = *approvalChain.Description
+	}
+
+	return resourceSdk.NewResource(
+		approvalChain.Name,
+		approvalChainResourceType,
+		approvalChain.ID,
+		resourceSdk.WithParentResourceID(parentResourceID),
+		resourceSdk.WithDescription(description),
+	)
+}
+
+func (o *approvalChainBuilder) List(
+	ctx context.Context,
+	parentResourceID *v2.ResourceId,
+	pToken *pagination.Token,
+) (
+	[]*v2.Resource,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	logger := ctxzap.Extract(ctx)
+	logger.Debug("Starting Approval Chains List", zap.String("token", pToken.Token))
+
+	outputResources := make([]*v2.Resource, 0)
+	var outputAnnotations annotations.Annotations
+
+	var target client.ApprovalChainsQueryResponse
+	response, ratelimitData, err := o.client.Query(
+		ctx,
+		client.ApprovalChainsQuery(pToken.Token),
+		&target,
+	)
+	outputAnnotations.WithRateLimiting(ratelimitData)
+	if err != nil {
+		return nil, "", outputAnnotations, err
+	}
+	defer response.Body.Close()
+
+	lastId := ""
+	for _, approvalChain := range target.ApprovalChains {
+		resource, err := approvalChainResource(approvalChain, parentResourceID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		outputResources = append(outputResources, resource)
+		lastId = strconv.Itoa(approvalChain.ID)
+	}
+
+	return outputResources, lastId, outputAnnotations, nil
+}
+
+func (o *approvalChainBuilder) Entitlements(
+	_ context.Context,
+	resource *v2.Resource,
+	_ *pagination.Token,
+) (
+	[]*v2.Entitlement,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	return []*v2.Entitlement{
+		entitlement.NewPermissionEntitlement(
+			resource,
+			approvalChainApproverEntitlementName,
+			entitlement.WithGrantableTo(userResourceType, approvalGroupResourceType),
+			entitlement.WithDisplayName(
+				fmt.Sprintf("%s Approval Chain Approver", resource.DisplayName),
+			),
+			entitlement.WithDescription(
+				fmt.Sprintf("Approver in the %s approval chain in Coupa", resource.DisplayName),
+			),
+			entitlement.WithAnnotation(&v2.EntitlementImmutable{}),
+		),
+	}, "", nil, nil
+}
+
+func (o *approvalChainBuilder) Grants(
+	ctx context.Context,
+	resource *v2.Resource,
+	_ *pagination.Token,
+) (
+	[]*v2.Grant,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	logger := ctxzap.Extract(ctx)
+
+	approvalChainId := resource.Id.Resource
+
+	logger.Debug(
+		"Starting Approval Chains Grants",
+		zap.String("approval_chain_id", approvalChainId),
+	)
+
+	outputGrants := make([]*v2.Grant, 0)
+	var outputAnnotations annotations.Annotations
+
+	var target client.ApprovalChainApproversQueryResponse
+	response, ratelimitData, err := o.client.Query(
+		ctx,
+		client.ApprovalChainApproversQuery(approvalChainId),
+		&target,
+	)
+	outputAnnotations.WithRateLimiting(ratelimitData)
+	if err != nil {
+		return nil, "", outputAnnotations, err
+	}
+	defer response.Body.Close()
+
+	// Every approval chain query must return _one_ approval chain.
+	if len(target.ApprovalChains) == 0 {
+		return outputGrants, "", outputAnnotations, nil
+	}
+
+	for _, approver := range target.ApprovalChains[0].Approvers {
+		approverId := strconv.Itoa(approver.ApproverId)
+		switch approver.ApproverType {
+		case client.ApproverTypeUser:
+			outputGrants = append(
+				outputGrants,
+				grant.NewGrant(
+					resource,
+					approvalChainApproverEntitlementName,
+					&v2.ResourceId{
+						ResourceType: userResourceType.Id,
+						Resource:     approverId,
+					},
+					grant.WithAnnotation(&v2.GrantImmutable{}),
+				),
+			)
+		case client.ApproverTypeApprovalGroup:
+			principal := &v2.ResourceId{
+				ResourceType: approvalGroupResourceType.Id,
+				Resource:     approverId,
+			}
+			outputGrants = append(
+				outputGrants,
+				grant.NewGrant(
+					resource,
+					approvalChainApproverEntitlementName,
+					principal,
+					grant.WithAnnotation(
+						&v2.GrantImmutable{},
+						&v2.GrantExpandable{
+							EntitlementIds: []string{
+								fmt.Sprintf(
+									"%s:%s:%s",
+									approvalGroupResourceType.Id,
+									approverId,
+									approvalGroupMemberEntitlementName,
+								),
+							},
+						},
+					),
+				),
+			)
+		default:
+			logger.Debug(
+				"baton-coupa: skipping unsupported approver type",
+				zap.String("approval_chain_id", approvalChainId),
+				zap.String("approver_type", approver.ApproverType),
+			)
+		}
+	}
+
+	return outputGrants, "", outputAnnotations, nil
+}
+
+func newApprovalChainBuilder(ctx context.Context, client *client.Client) *approvalChainBuilder {
+	return &approvalChainBuilder{
+		client: client,
+	}
+}
@@ -0,0 +1,298 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/conductorone/baton-coupa/pkg/connector/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	resourceSdk "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+const approvalGroupMemberEntitlementName = "member"
+
+type approvalGroupBuilder struct {
+	client     *client.Client
+	guardrails *guardrails
+}
+
+func (o *approvalGroupBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return approvalGroupResourceType
+}
+
+func approvalGroupResource(approvalGroup *client.ApprovalGroup, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	description := fmt.Sprintf("%s approval group in Coupa", approvalGroup.Name)
+	if approvalGroup.Description != nil && *approvalGroup.Description != "" {
+		description = *approvalGroup.Description
+	}
+
+	return resourceSdk.NewGroupResource(
+		approvalGroup.Name,
+		approvalGroupResourceType,
+		approvalGroup.ID,
+		[]resourceSdk.GroupTraitOption{},
+		resourceSdk.WithParentResourceID(parentResourceID),
+		resourceSdk.WithDescription(description),
+	)
+}
+
+func (o *approvalGroupBuilder) List(
+	ctx context.Context,
+	parentResourceID *v2.ResourceId,
+	pToken *pagination.Token,
+) (
+	[]*v2.Resource,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	logger := ctxzap.Extract(ctx)
+	logger.Debug("Starting Approval Groups List", zap.String("token", pToken.Token))
+
+	outputResources := make([]*v2.Resource, 0)
+	var outputAnnotations annotations.Annotations
+
+	var target client.ApprovalGroupsQueryResponse
+	response, ratelimitData, err := o.client.Query(
+		ctx,
+		client.ApprovalGroupsQuery(pToken.Token),
+		&target,
+	)
+	outputAnnotations.WithRateLimiting(ratelimitData)
+	if err != nil {
+		return nil, "", outputAnnotations, err
+	}
+	defer response.Body.Close()
+
+	lastId := ""
+	for _, approvalGroup := range target.ApprovalGroups {
+		resource, err := approvalGroupResource(approvalGroup, parentResourceID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		outputResources = append(outputResources, resource)
+		lastId = strconv.Itoa(approvalGroup.ID)
+	}
+
+	return outputResources, lastId, outputAnnotations, nil
+}
+
+func (o *approvalGroupBuilder) Entitlements(
+	_ context.Context,
+	resource *v2.Resource,
+	_ *pagination.Token,
+) (
+	[]*v2.Entitlement,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	return []*v2.Entitlement{
+		entitlement.NewAssignmentEntitlement(
+			resource,
+			approvalGroupMemberEntitlementName,
+			entitlement.WithGrantableTo(userResourceType),
+			entitlement.WithDisplayName(
+				fmt.Sprintf("%s Approval Group", resource.DisplayName),
+			),
+			entitlement.WithDescription(
+				fmt.Sprintf("%s approval group in Coupa", resource.DisplayName),
+			),
+		),
+	}, "", nil, nil
+}
+
+func (o *approvalGroupBuilder) Grants(
+	ctx context.Context,
+	resource *v2.Resource,
+	_ *pagination.Token,
+) (
+	[]*v2.Grant,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	logger := ctxzap.Extract(ctx)
+
+	approvalGroupId := resource.Id.Resource
+
+	logger.Debug(
+		"Starting Approval Groups Grants",
+		zap.String("approval_group_id", approvalGroupId),
+	)
+
+	outputGrants := make([]*v2.Grant, 0)
+	var outputAnnotations annotations.Annotations
+
+	memberIds, ratelimitData, err := o.getApprovalGroupMembers(ctx, approvalGroupId)
+	outputAnnotations.WithRateLimiting(ratelimitData)
+	if err != nil {
+		return nil, "", outputAnnotations, err
+	}
+
+	for _, memberId := range memberIds {
+		outputGrants = append(
+			outputGrants,
+			grant.NewGrant(
+				resource,
+				approvalGroupMemberEntitlementName,
+				&v2.ResourceId{
+					ResourceType: userResourceType.Id,
+					Resource:     strconv.Itoa(memberId),
+				},
+			),
+		)
+	}
+
+	return outputGrants, "", outputAnnotations, nil
+}
+
+func (o *approvalGroupBuilder) Grant(ctx context.Context, resource *v2.Resource, entitlement *v2.Entitlement) ([]*v2.Grant, annotations.Annotations, error) {
+	err := o.guardrails.checkGrant(entitlement.Resource.Id.ResourceType, entitlement.Resource.Id.Resource, resource.Id.Resource)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	l := ctxzap.Extract(ctx)
+
+	if resource.Id.ResourceType != userResourceType.Id {
+		return nil, nil, fmt.Errorf("baton-coupa: principal resource type is not %s", userResourceType.Id)
+	}
+
+	approvalGroupId, err := strconv.Atoi(entitlement.Resource.Id.Resource)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	userIdToAdd, err := strconv.Atoi(resource.Id.Resource)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	memberIds, _, err := o.getApprovalGroupMembers(ctx, entitlement.Resource.Id.Resource)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if slices.Contains(memberIds, userIdToAdd) {
+		return []*v2.Grant{}, annotations.New(&v2.GrantAlreadyExists{}), nil
+	}
+
+	newMemberIds := append(memberIds, userIdToAdd)
+
+	approvalGroup, _, err := o.client.SetApprovalGroupUsers(ctx, approvalGroupId, newMemberIds)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(approvalGroup.Users) != len(newMemberIds) {
+		l.Debug(
+			"baton-coupa: user not added to approval group",
+			zap.Any("response", approvalGroup.Users),
+		)
+		return nil, nil, errors.New("baton-coupa: failed to add user to approval group")
+	}
+
+	newGrant := grant.NewGrant(
+		entitlement.Resource,
+		approvalGroupMemberEntitlementName,
+		resource.Id,
+	)
+
+	return []*v2.Grant{newGrant}, nil, nil
+}
+
+func (o *approvalGroupBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	err := o.guardrails.checkUser(grant.Principal.Id.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	l := ctxzap.Extract(ctx)
+
+	if grant.Principal.Id.ResourceType != userResourceType.Id {
+		return nil, fmt.Errorf("baton-coupa: principal resource type is not %s", userResourceType.Id)
+	}
+
+	approvalGroupId, err := strconv.Atoi(grant.Entitlement.Resource.Id.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	userIdToRemove, err := strconv.Atoi(grant.Principal.Id.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	memberIds, _, err := o.getApprovalGroupMembers(ctx, grant.Entitlement.Resource.Id.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	index := slices.Index(memberIds, userIdToRemove)
+	if index < 0 {
+		l.Info(
+			"baton-coupa: user not found in approval group",
+		)
+
+		return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+	}
+
+	newMemberIds := slices.Delete(memberIds, index, index+1)
+
+	approvalGroup, _, err := o.client.SetApprovalGroupUsers(ctx, approvalGroupId, newMemberIds)
+	if err != nil {
+		l.Error(
+			"baton-coupa: error setting approval group users",
+			zap.Error(err),
+			zap.Ints("users", newMemberIds),
+		)
+		return nil, err
+	}
+
+	if len(approvalGroup.Users) != len(newMemberIds) {
+		return nil, errors.New("baton-coupa: user was not removed from approval group")
+	}
+
+	return nil, nil
+}
+
+func (o *approvalGroupBuilder) getApprovalGroupMembers(ctx context.Context, approvalGroupId string) ([]int, *v2.RateLimitDescription, error) {
+	var target client.ApprovalGroupMembersQueryResponse
+	response, ratelimitData, err := o.client.Query(
+		ctx,
+		client.ApprovalGroupMembersQuery(approvalGroupId),
+		&target,
+	)
+	if err != nil {
+		return nil, ratelimitData, err
+	}
+	defer response.Body.Close()
+
+	memberIds := make([]int, 0)
+
+	// Every approval group query must return _one_ approval group.
+	if len(target.ApprovalGroups) != 0 {
+		for _, membership := range target.ApprovalGroups[0].Users {
+			memberIds = append(memberIds, membership.Id)
+		}
+	}
+
+	return memberIds, ratelimitData, nil
+}
+
+func newApprovalGroupBuilder(ctx context.Context, client *client.Client, guardrails *guardrails) *approvalGroupBuilder {
+	return &approvalGroupBuilder{
+		client:     client,
+		guardrails: guardrails,
+	}
+}
@@ -0,0 +1,413 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/conductorone/baton-coupa/pkg/connector/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	resourceSdk "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	approvalLimitRequisitionEntitlementName = "requisition"
+	approvalLimitExpenseEntitlementName     = "expense"
+)
+
+// approvalLimitTypes maps each approval limit entitlement to the user
+// attribute that assigns it.
+var approvalLimitTypes = map[string]client.ApprovalLimitType{
+	approvalLimitRequisitionEntitlementName: client.ApprovalLimitTypeRequisition,
+	approvalLimitExpenseEntitlementName:     client.ApprovalLimitTypeExpense,
+}
+
+type approvalLimitBuilder struct {
+	client     *client.Client
+	guardrails *guardrails
+}
+
+func (o *approvalLimitBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return approvalLimitResourceType
+}
+
+func approvalLimitResource(approvalLimit *client.ApprovalLimit, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	name := approvalLimit.Name
+	if name == "" {
+		name = fmt.Sprintf("%s %s", approvalLimit.Amount, approvalLimitCurrency(approvalLimit))
+	}
+
+	return resourceSdk.NewResource(
+		name,
+		approvalLimitResourceType,
+		approvalLimit.ID,
+		resourceSdk.WithParentResourceID(parentResourceID),
+		resourceSdk.WithDescription(
+			fmt.Sprintf(
+				"Approval limit of %s %s in Coupa",
+				approvalLimit.Amount,
+				approvalLimitCurrency(approvalLimit),
+			),
+		),
+	)
+}
+
+func approvalLimitCurrency(approvalLimit *client.ApprovalLimit) string {
+	if approvalLimit.Currency == nil {
+		return ""
+	}
+	return approvalLimit.Currency.Code
+}
+
+func approvalLimitGrantMetadata(approvalLimit *client.ApprovalLimit) map[string]interface{} {
+	return map[string]interface{}{
+		"amount":   approvalLimit.Amount,
+		"currency": approvalLimitCurrency(approvalLimit),
+	}
+}
+
+func (o *approvalLimitBuilder) List(
+	ctx context.Context,
+	parentResourceID *v2.ResourceId,
+	pToken *pagination.Token,
+) (
+	[]*v2.Resource,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	logger := ctxzap.Extract(ctx)
+	logger.Debug("Starting Approval Limits List", zap.String("token", pToken.Token))
+
+	outputResources := make([]*v2.Resource, 0)
+	var outputAnnotations annotations.Annotations
+
+	var target client.ApprovalLimitsQueryResponse
+	response, ratelimitData, err := o.client.Query(
+		ctx,
+		client.ApprovalLimitsQuery(pToken.Token),
+		&target,
+	)
+	outputAnnotations.WithRateLimiting(ratelimitData)
+	if err != nil {
+		return nil, "", outputAnnotations, err
+	}
+	defer response.Body.Close()
+
+	lastId := ""
+	for _, approvalLimit := range target.ApprovalLimits {
+		resource, err := approvalLimitResource(approvalLimit, parentResourceID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		outputResources = append(outputResources, resource)
+		lastId = strconv.Itoa(approvalLimit.ID)
+	}
+
+	return outputResources, lastId, outputAnnotations, nil
+}
+
+func (o *approvalLimitBuilder) Entitlements(
+	_ context.Context,
+	resource *v2.Resource,
+	_ *pagination.Token,
+) (
+	[]*v2.Entitlement,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	return []*v2.Entitlement{
+		entitlement.NewAssignmentEntitlement(
+			resource,
+			approvalLimitRequisitionEntitlementName,
+			entitlement.WithGrantableTo(userResourceType),
+			entitlement.WithDisplayName(
+				fmt.Sprintf("%s Requisition Approval Limit", resource.DisplayName),
+			),
+			entitlement.WithDescription(
+				fmt.Sprintf("%s requisition approval limit in Coupa", resource.DisplayName),
+			),
+		),
+		entitlement.NewAssignmentEntitlement(
+			resource,
+			approvalLimitExpenseEntitlementName,
+			entitlement.WithGrantableTo(userResourceType),
+			entitlement.WithDisplayName(
+				fmt.Sprintf("%s Expense Approval Limit", resource.DisplayName),
+			),
+			entitlement.WithDescription(
+				fmt.Sprintf("%s expense approval limit in Coupa", resource.DisplayName),
+			),
+		),
+	}, "", nil, nil
+}
+
+// Grants lists the users holding the approval limit, first as requisition
+// approval limit and then as expense approval limit.
+func (o *approvalLimitBuilder) Grants(
+	ctx context.Context,
+	resource *v2.Resource,
+	pToken *pagination.Token,
+) (
+	[]*v2.Grant,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	logger := ctxzap.Extract(ctx)
+
+	approvalLimitId := resource.Id.Resource
+
+	logger.Debug(
+		"Starting Approval Limits Grants",
+		zap.String("approval_limit_id", approvalLimitId),
+		zap.String("token", pToken.Token),
+	)
+
+	bag := &pagination.Bag{}
+	err := bag.Unmarshal(pToken.Token)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if bag.Current() == nil {
+		bag.Push(pagination.PageState{ResourceTypeID: approvalLimitExpenseEntitlementName})
+		bag.Push(pagination.PageState{ResourceTypeID: approvalLimitRequisitionEntitlementName})
+	}
+
+	entitlementName := bag.ResourceTypeID()
+	limitType, ok := approvalLimitTypes[entitlementName]
+	if !ok {
+		return nil, "", nil, fmt.Errorf("baton-coupa: unknown approval limit entitlement %s", entitlementName)
+	}
+
+	outputGrants := make([]*v2.Grant, 0)
+	var outputAnnotations annotations.Annotations
+
+	approvalLimit, ratelimitData, err := o.getApprovalLimit(ctx, approvalLimitId)
+	outputAnnotations.WithRateLimiting(ratelimitData)
+	if err != nil {
+		return nil, "", outputAnnotations, err
+	}
+
+	var target client.ApprovalLimitGrantsQueryResponse
+	response, ratelimitData, err := o.client.Query(
+		ctx,
+		client.ApprovalLimitGrantQuery(limitType, approvalLimitId, bag.PageToken()),
+		&target,
+	)
+	outputAnnotations.WithRateLimiting(ratelimitData)
+	if err != nil {
+		return nil, "", outputAnnotations, err
+	}
+	defer response.Body.Close()
+
+	lastId := ""
+	for _, user := range target.Users {
+		userId := strconv.Itoa(user.Id)
+		outputGrants = append(
+			outputGrants,
+			grant.NewGrant(
+				resource,
+				entitlementName,
+				&v2.ResourceId{
+					ResourceType: userResourceType.Id,
+					Resource:     userId,
+				},
+				grant.WithGrantMetadata(approvalLimitGrantMetadata(approvalLimit)),
+			),
+		)
+		lastId = userId
+	}
+
+	nextToken, err := bag.NextToken(lastId)
+	if err != nil {
+		return nil, "", outputAnnotations, err
+	}
+
+	return outputGrants, nextToken, outputAnnotations, nil
+}
+
+func (o *approvalLimitBuilder) Grant(ctx context.Context, resource *v2.Resource, entitlement *v2.Entitlement) ([]*v2.Grant, annotations.Annotations, error) {
+	err := o.guardrails.checkGrant(entitlement.Resource.Id.ResourceType, entitlement.Resource.Id.Resource, resource.Id.Resource)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if resource.Id.ResourceType != userResourceType.Id {
+		return nil, nil, fmt.Errorf("baton-coupa: principal resource type is not %s", userResourceType.Id)
+	}
+
+	limitType, ok := approvalLimitTypes[entitlement.Slug]
+	if !ok {
+		return nil, nil, fmt.Errorf("baton-coupa: unknown approval limit entitlement %s", entitlement.Slug)
+	}
+
+	approvalLimitId, err := strconv.Atoi(entitlement.Resource.Id.Resource)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	userId, err := strconv.Atoi(resource.Id.Resource)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = o.guardrails.checkSod(ctx, o.client, userId, sodAccessOf(entitlement))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	approvalLimit, _, err := o.getApprovalLimit(ctx, entitlement.Resource.Id.Resource)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user, err := o.getUserApprovalLimits(ctx, userId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	newGrant := grant.NewGrant(
+		entitlement.Resource,
+		entitlement.Slug,
+		resource.Id,
+		grant.WithGrantMetadata(approvalLimitGrantMetadata(approvalLimit)),
+	)
+
+	// A user has a single approval limit of each type, so granting another one
+	// would silently replace the current one and leave its grant stale.
+	if current := limitType.Of(user); current != nil {
+		if current.Id == approvalLimitId {
+			return []*v2.Grant{newGrant}, annotations.New(&v2.GrantAlreadyExists{}), nil
+		}
+		return nil, nil, status.Errorf(
+			codes.FailedPrecondition,
+			"baton-coupa: user %d already has the %s approval limit %d, revoke it first",
+			userId,
+			entitlement.Slug,
+			current.Id,
+		)
+	}
+
+	userResponse, _, err := o.client.SetApprovalLimit(ctx, userId, limitType, &approvalLimitId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if current := limitType.Of(userResponse.UserApprovalLimits()); current == nil || current.Id != approvalLimitId {
+		return nil, nil, errors.New("baton-coupa: approval limit not set")
+	}
+
+	return []*v2.Grant{newGrant}, nil, nil
+}
+
+func (o *approvalLimitBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	err := o.guardrails.checkUser(grant.Principal.Id.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	l := ctxzap.Extract(ctx)
+
+	if grant.Principal.Id.ResourceType != userResourceType.Id {
+		return nil, fmt.Errorf("baton-coupa: principal resource type is not %s", userResourceType.Id)
+	}
+
+	limitType, ok := approvalLimitTypes[grant.Entitlement.Slug]
+	if !ok {
+		return nil, fmt.Errorf("baton-coupa: unknown approval limit entitlement %s", grant.Entitlement.Slug)
+	}
+
+	approvalLimitId, err := strconv.Atoi(grant.Entitlement.Resource.Id.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	userId, err := strconv.Atoi(grant.Principal.Id.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := o.getUserApprovalLimits(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if current := limitType.Of(user); current == nil || current.Id != approvalLimitId {
+		l.Info(
+			"baton-coupa: approval limit not found in user",
+		)
+
+		return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+	}
+
+	userResponse, _, err := o.client.SetApprovalLimit(ctx, userId, limitType, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if limitType.Of(userResponse.UserApprovalLimits()) != nil {
+		return nil, errors.New("baton-coupa: approval limit was not removed")
+	}
+
+	return nil, nil
+}
+
+func (o *approvalLimitBuilder) getApprovalLimit(ctx context.Context, approvalLimitId string) (*client.ApprovalLimit, *v2.RateLimitDescription, error) {
+	var target client.ApprovalLimitsQueryResponse
+	response, ratelimitData, err := o.client.Query(
+		ctx,
+		client.ApprovalLimitQuery(approvalLimitId),
+		&target,
+	)
+	if err != nil {
+		return nil, ratelimitData, err
+	}
+	defer response.Body.Close()
+
+	if len(target.ApprovalLimits) == 0 {
+		return nil, ratelimitData, errors.New("baton-coupa: approval limit not found")
+	}
+
+	return target.ApprovalLimits[0], ratelimitData, nil
+}
+
+func (o *approvalLimitBuilder) getUserApprovalLimits(ctx context.Context, userId int) (*client.UserApprovalLimits, error) {
+	var target client.UserApprovalLimitsResponse
+	response, _, err := o.client.Query(
+		ctx,
+		client.GetUserApprovalLimits(userId),
+		&target,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if len(target.Users) == 0 {
+		return nil, errors.New("baton-coupa: user not found")
+	}
+
+	if len(target.Users) > 1 {
+		return nil, fmt.Errorf("baton-coupa: multiple users found for id %d", userId)
+	}
+
+	return &target.Users[0], nil
+}
+
+func newApprovalLimitBuilder(ctx context.Context, client *client.Client, guardrails *guardrails) *approvalLimitBuilder {
+	return &approvalLimitBuilder{
+		client:     client,
+		guardrails: guardrails,
+	}
+}
@@ -0,0 +1,185 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/conductorone/baton-coupa/pkg/connector/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	resourceSdk "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+const budgetLineOwnerEntitlementName = "owner"
+
+type budgetLineBuilder struct {
+	client *client.Client
+	// period limits the sync to the budget lines of a budget period.
+	period string
+}
+
+func (o *budgetLineBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return budgetLineResourceType
+}
+
+func budgetLineResource(budgetLine *client.BudgetLine, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	name := budgetLine.Code
+	if budgetLine.Period != nil && budgetLine.Period.Name != "" {
+		name = fmt.Sprintf("%s (%s)", budgetLine.Code, budgetLine.Period.Name)
+	}
+
+	currency := ""
+	if budgetLine.Currency != nil {
+		currency = budgetLine.Currency.Code
+	}
+
+	description := fmt.Sprintf("%s budget line of %s %s in Coupa", budgetLine.Code, budgetLine.Amount, currency)
+	if budgetLine.Description != nil && *budgetLine.Description != "" {
+		description = *budgetLine.Description
+	}
+
+	return resourceSdk.NewResource(
+		name,
+		budgetLineResourceType,
+		budgetLine.ID,
+		resourceSdk.WithParentResourceID(parentResourceID),
+		resourceSdk.WithDescription(description),
+	)
+}
+
+func (o *budgetLineBuilder) List(
+	ctx context.Context,
+	parentResourceID *v2.ResourceId,
+	pToken *pagination.Token,
+) (
+	[]*v2.Resource,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	logger := ctxzap.Extract(ctx)
+	logger.Debug(
+		"Starting Budget Lines List",
+		zap.String("period", o.period),
+		zap.String("token", pToken.Token),
+	)
+
+	outputResources := make([]*v2.Resource, 0)
+	var outputAnnotations annotations.Annotations
+
+	var target client.BudgetLinesQueryResponse
+	response, ratelimitData, err := o.client.Query(
+		ctx,
+		client.BudgetLinesQuery(o.period, pToken.Token),
+		&target,
+	)
+	outputAnnotations.WithRateLimiting(ratelimitData)
+	if err != nil {
+		return nil, "", outputAnnotations, err
+	}
+	defer response.Body.Close()
+
+	lastId := ""
+	for _, budgetLine := range target.BudgetLines {
+		resource, err := budgetLineResource(budgetLine, parentResourceID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		outputResources = append(outputResources, resource)
+		lastId = strconv.Itoa(budgetLine.ID)
+	}
+
+	return outputResources, lastId, outputAnnotations, nil
+}
+
+func (o *budgetLineBuilder) Entitlements(
+	_ context.Context,
+	resource *v2.Resource,
+	_ *pagination.Token,
+) (
+	[]*v2.Entitlement,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	return []*v2.Entitlement{
+		entitlement.NewAssignmentEntitlement(
+			resource,
+			budgetLineOwnerEntitlementName,
+			entitlement.WithGrantableTo(userResourceType),
+			entitlement.WithDisplayName(
+				fmt.Sprintf("%s Budget Line Owner", resource.DisplayName),
+			),
+			entitlement.WithDescription(
+				fmt.Sprintf("Owner of the %s budget line in Coupa", resource.DisplayName),
+			),
+		),
+	}, "", nil, nil
+}
+
+func (o *budgetLineBuilder) Grants(
+	ctx context.Context,
+	resource *v2.Resource,
+	_ *pagination.Token,
+) (
+	[]*v2.Grant,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	logger := ctxzap.Extract(ctx)
+
+	budgetLineId := resource.Id.Resource
+
+	logger.Debug(
+		"Starting Budget Lines Grants",
+		zap.String("budget_line_id", budgetLineId),
+	)
+
+	outputGrants := make([]*v2.Grant, 0)
+	var outputAnnotations annotations.Annotations
+
+	var target client.BudgetLineOwnersQueryResponse
+	response, ratelimitData, err := o.client.Query(
+		ctx,
+		client.BudgetLineOwnersQuery(budgetLineId),
+		&target,
+	)
+	outputAnnotations.WithRateLimiting(ratelimitData)
+	if err != nil {
+		return nil, "", outputAnnotations, err
+	}
+	defer response.Body.Close()
+
+	// Every budget line query must return _one_ budget line.
+	if len(target.BudgetLines) != 0 {
+		for _, owner := range target.BudgetLines[0].Owners {
+			outputGrants = append(
+				outputGrants,
+				grant.NewGrant(
+					resource,
+					budgetLineOwnerEntitlementName,
+					&v2.ResourceId{
+						ResourceType: userResourceType.Id,
+						Resource:     strconv.Itoa(owner.Id),
+					},
+				),
+			)
+		}
+	}
+
+	return outputGrants, "", outputAnnotations, nil
+}
+
+func newBudgetLineBuilder(ctx context.Context, client *client.Client, period string) *budgetLineBuilder {
+	return &budgetLineBuilder{
+		client: client,
+		period: period,
+	}
+}
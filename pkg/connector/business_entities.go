@@ -0,0 +1,195 @@
+package connector
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/conductorone/baton-coupa/pkg/connector/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	resourceSdk "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+const businessEntityMemberEntitlementName = "member"
+
+type businessEntityBuilder struct {
+	client *client.Client
+}
+
+func (o *businessEntityBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return businessEntityResourceType
+}
+
+func businessEntityResource(businessEntity *client.BusinessEntity, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	description := fmt.Sprintf("%s business entity in Coupa", businessEntity.Name)
+	if businessEntity.Description != nil && *businessEntity.Description != "" {
+		description = *businessEntity.Description
+	}
+
+	return resourceSdk.NewResource(
+		businessEntity.Name,
+		businessEntityResourceType,
+		businessEntity.ID,
+		resourceSdk.WithParentResourceID(parentResourceID),
+		resourceSdk.WithDescription(description),
+		resourceSdk.WithAnnotation(
+			&v2.ChildResourceType{ResourceTypeId: userResourceType.Id},
+			&v2.ChildResourceType{ResourceTypeId: groupResourceType.Id},
+		),
+	)
+}
+
+// listedUnder reports whether a user or group in the given business entities
+// is listed under the parent resource. Each one is listed once, so that its
+// parent does not depend on the sync order: under its business entity with
+// the lowest id, or at the top level when it is in none. The memberships in
+// the other business entities are still synced as grants.
+func listedUnder(businessEntities []client.ResourceId, parentResourceID *v2.ResourceId) bool {
+	if len(businessEntities) == 0 {
+		return parentResourceID == nil
+	}
+	if parentResourceID == nil || parentResourceID.ResourceType != businessEntityResourceType.Id {
+		return false
+	}
+
+	parent := slices.MinFunc(businessEntities, func(a, b client.ResourceId) int {
+		return cmp.Compare(a.Id, b.Id)
+	})
+	return strconv.Itoa(parent.Id) == parentResourceID.Resource
+}
+
+func (o *businessEntityBuilder) List(
+	ctx context.Context,
+	parentResourceID *v2.ResourceId,
+	pToken *pagination.Token,
+) (
+	[]*v2.Resource,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	logger := ctxzap.Extract(ctx)
+	logger.Debug("Starting Business Entities List", zap.String("token", pToken.Token))
+
+	outputResources := make([]*v2.Resource, 0)
+	var outputAnnotations annotations.Annotations
+
+	var target client.BusinessEntitiesQueryResponse
+	response, ratelimitData, err := o.client.Query(
+		ctx,
+		client.BusinessEntitiesQuery(pToken.Token),
+		&target,
+	)
+	outputAnnotations.WithRateLimiting(ratelimitData)
+	if err != nil {
+		return nil, "", outputAnnotations, err
+	}
+	defer response.Body.Close()
+
+	lastId := ""
+	for _, businessEntity := range target.BusinessEntities {
+		resource, err := businessEntityResource(businessEntity, parentResourceID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		outputResources = append(outputResources, resource)
+		lastId = strconv.Itoa(businessEntity.ID)
+	}
+
+	return outputResources, lastId, outputAnnotations, nil
+}
+
+func (o *businessEntityBuilder) Entitlements(
+	_ context.Context,
+	resource *v2.Resource,
+	_ *pagination.Token,
+) (
+	[]*v2.Entitlement,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	return []*v2.Entitlement{
+		entitlement.NewAssignmentEntitlement(
+			resource,
+			businessEntityMemberEntitlementName,
+			entitlement.WithGrantableTo(userResourceType),
+			entitlement.WithDisplayName(
+				fmt.Sprintf("%s Business Entity Member", resource.DisplayName),
+			),
+			entitlement.WithDescription(
+				fmt.Sprintf("Member of the %s business entity in Coupa", resource.DisplayName),
+			),
+		),
+	}, "", nil, nil
+}
+
+// Grants returns the users of the business entity as its members.
+func (o *businessEntityBuilder) Grants(
+	ctx context.Context,
+	resource *v2.Resource,
+	pToken *pagination.Token,
+) (
+	[]*v2.Grant,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	logger := ctxzap.Extract(ctx)
+
+	businessEntityId := resource.Id.Resource
+
+	logger.Debug(
+		"Starting Business Entities Grants",
+		zap.String("business_entity_id", businessEntityId),
+		zap.String("token", pToken.Token),
+	)
+
+	outputGrants := make([]*v2.Grant, 0)
+	var outputAnnotations annotations.Annotations
+
+	var target client.UsersQueryResponse
+	response, ratelimitData, err := o.client.Query(
+		ctx,
+		client.BusinessEntityUsersQuery(businessEntityId, pToken.Token),
+		&target,
+	)
+	outputAnnotations.WithRateLimiting(ratelimitData)
+	if err != nil {
+		return nil, "", outputAnnotations, err
+	}
+	defer response.Body.Close()
+
+	lastId := ""
+	for _, user := range target.Users {
+		userId := strconv.Itoa(user.ID)
+		outputGrants = append(
+			outputGrants,
+			grant.NewGrant(
+				resource,
+				businessEntityMemberEntitlementName,
+				&v2.ResourceId{
+					ResourceType: userResourceType.Id,
+					Resource:     userId,
+				},
+			),
+		)
+		lastId = userId
+	}
+
+	return outputGrants, lastId, outputAnnotations, nil
+}
+
+func newBusinessEntityBuilder(ctx context.Context, client *client.Client) *businessEntityBuilder {
+	return &businessEntityBuilder{
+		client: client,
+	}
+}
@@ -0,0 +1,56 @@
+package connector
+
+import (
+	"testing"
+
+	"github.com/conductorone/baton-coupa/pkg/connector/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListedUnder(t *testing.T) {
+	businessEntity := func(id string) *v2.ResourceId {
+		return &v2.ResourceId{ResourceType: businessEntityResourceType.Id, Resource: id}
+	}
+
+	testCases := []struct {
+		message          string
+		businessEntities []client.ResourceId
+		parentResourceID *v2.ResourceId
+		listed           bool
+	}{
+		{
+			message: "no business entity at the top level",
+			listed:  true,
+		}, {
+			message:          "no business entity under a business entity",
+			parentResourceID: businessEntity("3"),
+		}, {
+			message:          "business entity at the top level",
+			businessEntities: []client.ResourceId{{Id: 3}},
+		}, {
+			message:          "own business entity",
+			businessEntities: []client.ResourceId{{Id: 3}},
+			parentResourceID: businessEntity("3"),
+			listed:           true,
+		}, {
+			message:          "lowest of several business entities",
+			businessEntities: []client.ResourceId{{Id: 8}, {Id: 3}},
+			parentResourceID: businessEntity("3"),
+			listed:           true,
+		}, {
+			message:          "other of several business entities",
+			businessEntities: []client.ResourceId{{Id: 8}, {Id: 3}},
+			parentResourceID: businessEntity("8"),
+		}, {
+			message:          "other parent resource type",
+			businessEntities: []client.ResourceId{{Id: 3}},
+			parentResourceID: &v2.ResourceId{ResourceType: supplierResourceType.Id, Resource: "3"},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.message, func(t *testing.T) {
+			require.Equal(t, testCase.listed, listedUnder(testCase.businessEntities, testCase.parentResourceID))
+		})
+	}
+}
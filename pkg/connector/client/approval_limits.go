@@ -0,0 +1,88 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+)
+
+// ApprovalLimitType is the user attribute an approval limit is assigned
+// through. Its value is the attribute name in the users REST API.
+type ApprovalLimitType string
+
+const (
+	ApprovalLimitTypeRequisition ApprovalLimitType = "requisition-approval-limit"
+	ApprovalLimitTypeExpense     ApprovalLimitType = "expense-approval-limit"
+)
+
+func (t ApprovalLimitType) queryField() string {
+	switch t {
+	case ApprovalLimitTypeExpense:
+		return "expense_approval_limit"
+	default:
+		return "requisition_approval_limit"
+	}
+}
+
+// Of returns the id of the approval limit of this type assigned to the user,
+// or nil if there is none.
+func (t ApprovalLimitType) Of(user *UserApprovalLimits) *ResourceId {
+	switch t {
+	case ApprovalLimitTypeExpense:
+		return user.ExpenseApprovalLimit
+	default:
+		return user.RequisitionApprovalLimit
+	}
+}
+
+// SetApprovalLimit sets the requisition or expense approval limit of a user.
+// A nil approvalLimitId clears the limit.
+// https://compass.coupa.com/en-us/products/product-documentation/integration-technical-documentation/the-coupa-core-api/resources/reference-data-resources/users-api-(users)
+func (c *Client) SetApprovalLimit(
+	ctx context.Context,
+	userId int,
+	limitType ApprovalLimitType,
+	approvalLimitId *int,
+) (
+	*UserApprovalLimitsApiResponse,
+	*v2.RateLimitDescription,
+	error,
+) {
+	err := c.Initialize(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	request := map[ApprovalLimitType]*ResourceId{
+		limitType: nil,
+	}
+	if approvalLimitId != nil {
+		request[limitType] = &ResourceId{Id: *approvalLimitId}
+	}
+
+	var userResponse UserApprovalLimitsApiResponse
+
+	response, rateLimit, err := c.doRestRequest(
+		ctx,
+		http.MethodPut,
+		c.baseUrl.JoinPath(fmt.Sprintf(setApprovalLimitsPath, userId)),
+		request,
+		&userResponse,
+	)
+	if err != nil {
+		return nil, rateLimit, err
+	}
+	defer response.Body.Close()
+
+	return &userResponse, rateLimit, nil
+}
+
+func (r *UserApprovalLimitsApiResponse) UserApprovalLimits() *UserApprovalLimits {
+	return &UserApprovalLimits{
+		Id:                       r.Id,
+		RequisitionApprovalLimit: r.RequisitionApprovalLimit,
+		ExpenseApprovalLimit:     r.ExpenseApprovalLimit,
+	}
+}
@@ -0,0 +1,55 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+)
+
+const (
+	ApproverTypeUser          = "User"
+	ApproverTypeApprovalGroup = "ApprovalGroup"
+)
+
+// SetApprovalGroupUsers sets the members of an approval group.
+// https://compass.coupa.com/en-us/products/product-documentation/integration-technical-documentation/the-coupa-core-api/resources/reference-data-resources/approval-groups-api
+func (c *Client) SetApprovalGroupUsers(
+	ctx context.Context,
+	approvalGroupId int,
+	userIDs []int,
+) (
+	*ApprovalGroupApiResponse,
+	*v2.RateLimitDescription,
+	error,
+) {
+	err := c.Initialize(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	request := struct {
+		Users []ResourceId `json:"users"`
+	}{}
+
+	for _, userId := range userIDs {
+		request.Users = append(request.Users, ResourceId{Id: userId})
+	}
+
+	var approvalGroupResponse ApprovalGroupApiResponse
+
+	response, rateLimit, err := c.doRestRequest(
+		ctx,
+		http.MethodPut,
+		c.baseUrl.JoinPath(fmt.Sprintf(setApprovalGroupUsersPath, approvalGroupId)),
+		request,
+		&approvalGroupResponse,
+	)
+	if err != nil {
+		return nil, rateLimit, err
+	}
+	defer response.Body.Close()
+
+	return &approvalGroupResponse, rateLimit, nil
+}
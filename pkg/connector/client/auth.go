@@ -3,26 +3,37 @@ package client
 import (
 	"context"
 	"net/url"
+	"slices"
+	"strings"
 
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/clientcredentials"
 )
 
 var (
+	// ScopesReadOnly are requested for syncing when no scopes are given.
 	ScopesReadOnly = []string{
+		"core.approval.read",
+		"core.budget.read",
 		"core.business_entity.read",
 		"core.common.read",
+		"core.contract.read",
+		"core.inventory.common.read",
+		"core.invoice.read",
+		"core.requisition.read",
+		"core.supplier.read",
 		"core.user_group.read",
 		"core.user.read",
 		"email login",
 		"openid",
 		"profile",
 	}
-	ScopesReadWrite = append(
-		ScopesReadOnly,
+	// ScopesWrite are added to the sync scopes when provisioning.
+	ScopesWrite = []string{
+		"core.approval.write",
 		"core.user_group.write",
 		"core.user.write",
-	)
+	}
 )
 
 func getTokenSource(
@@ -41,3 +52,22 @@ func getTokenSource(
 	}
 	return cfg.TokenSource(ctx)
 }
+
+// missingScopes returns the scopes in wanted that were not granted to the
+// token. Coupa lists the granted scopes in the token response; when it does
+// not, nothing is reported as missing.
+func missingScopes(token *oauth2.Token, wanted []string) []string {
+	granted, ok := token.Extra("scope").(string)
+	if !ok || granted == "" {
+		return nil
+	}
+
+	grantedScopes := strings.Fields(granted)
+	missing := make([]string, 0)
+	for _, scope := range wanted {
+		if !slices.Contains(grantedScopes, scope) {
+			missing = append(missing, scope)
+		}
+	}
+	return missing
+}
@@ -3,8 +3,12 @@ package client
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/url"
+	"slices"
+	"strings"
 
 	"go.uber.org/zap"
 
@@ -15,6 +19,23 @@ import (
 	"golang.org/x/oauth2"
 )
 
+var ErrMissingWriteScopes = errors.New("baton-coupa: provisioning is enabled but the OAuth client is missing the write scopes")
+
+// GraphqlError is an error returned in the body of a GraphQL response.
+type GraphqlError struct {
+	Message string
+}
+
+func (e *GraphqlError) Error() string {
+	return e.Message
+}
+
+// IsSchemaError tells whether the query asked for a field the schema of the
+// instance does not have.
+func (e *GraphqlError) IsSchemaError() bool {
+	return strings.Contains(e.Message, "doesn't exist on type")
+}
+
 type innerGraphqlResponse struct {
 	Data   *json.RawMessage `json:"data,omitempty"`
 	Errors []struct {
@@ -27,6 +48,9 @@ type Client struct {
 	readOnlyToken        string
 	readWriteToken       string
 	initialized          bool
+	provisioning         bool
+	dryRun               bool
+	journal              *journal
 	ReadOnlyTokenSource  oauth2.TokenSource
 	readWriteTokenSource oauth2.TokenSource
 	wrapper              *uhttp.BaseHttpClient
@@ -37,6 +61,13 @@ func New(
 	instanceUrl string,
 	clientId string,
 	clientSecret string,
+	writeClientId string,
+	writeClientSecret string,
+	scopes []string,
+	provisioning bool,
+	dryRun bool,
+	journalPath string,
+	journalKey string,
 ) (*Client, error) {
 	httpClient, err := uhttp.NewClient(
 		ctx,
@@ -60,8 +91,21 @@ func New(
 	}
 
 	coupaClient := &Client{
-		baseUrl: baseUrl,
-		wrapper: uhttp.NewBaseHttpClient(httpClient),
+		baseUrl:      baseUrl,
+		provisioning: provisioning,
+		dryRun:       dryRun,
+		wrapper:      uhttp.NewBaseHttpClient(httpClient),
+	}
+
+	if journalPath != "" {
+		coupaClient.journal, err = openJournal(journalPath, journalKey)
+		if err != nil {
+			return nil, fmt.Errorf("baton-coupa: opening journal: %w", err)
+		}
+	}
+
+	if len(scopes) == 0 {
+		scopes = ScopesReadOnly
 	}
 
 	if clientId != "" && clientSecret != "" {
@@ -70,15 +114,25 @@ func New(
 			baseUrl,
 			clientId,
 			clientSecret,
-			ScopesReadOnly...,
-		)
-		coupaClient.readWriteTokenSource = getTokenSource(
-			ctx,
-			baseUrl,
-			clientId,
-			clientSecret,
-			ScopesReadWrite...,
+			scopes...,
 		)
+		// The write scopes are only requested when provisioning is enabled
+		// so that syncing works with a read-only OAuth client.
+		if provisioning {
+			// A separate credential can be used for provisioning so that
+			// it can be rotated and audited on its own.
+			if writeClientId == "" || writeClientSecret == "" {
+				writeClientId = clientId
+				writeClientSecret = clientSecret
+			}
+			coupaClient.readWriteTokenSource = getTokenSource(
+				ctx,
+				baseUrl,
+				writeClientId,
+				writeClientSecret,
+				append(slices.Clone(scopes), ScopesWrite...)...,
+			)
+		}
 	}
 
 	return coupaClient, nil
@@ -124,13 +178,24 @@ func (c *Client) Initialize(ctx context.Context) error {
 		return err
 	}
 
-	rwtoken, err := c.readWriteTokenSource.Token()
-	if err != nil {
-		return err
+	if c.provisioning {
+		rwtoken, err := c.readWriteTokenSource.Token()
+		if err != nil {
+			return fmt.Errorf(
+				"baton-coupa: provisioning is enabled but a token with the write scopes (%s) could not be fetched: %w",
+				strings.Join(ScopesWrite, ", "),
+				err,
+			)
+		}
+
+		missing := missingScopes(rwtoken, ScopesWrite)
+		if len(missing) > 0 {
+			return fmt.Errorf("%w: %s", ErrMissingWriteScopes, strings.Join(missing, ", "))
+		}
+		c.readWriteToken = rwtoken.AccessToken
 	}
 
 	c.readOnlyToken = rtoken.AccessToken
-	c.readWriteToken = rwtoken.AccessToken
 	c.initialized = true
 	return nil
 }
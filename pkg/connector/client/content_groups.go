@@ -0,0 +1,51 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+)
+
+// SetContentGroups sets the content groups of a user.
+// https://compass.coupa.com/en-us/products/product-documentation/integration-technical-documentation/the-coupa-core-api/resources/reference-data-resources/users-api-(users)
+func (c *Client) SetContentGroups(
+	ctx context.Context,
+	userId int,
+	contentGroupIds []int,
+) (
+	*UserContentGroupsApiResponse,
+	*v2.RateLimitDescription,
+	error,
+) {
+	err := c.Initialize(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	request := struct {
+		ContentGroups []ResourceId `json:"content-groups"`
+	}{
+		ContentGroups: make([]ResourceId, 0, len(contentGroupIds)),
+	}
+	for _, contentGroupId := range contentGroupIds {
+		request.ContentGroups = append(request.ContentGroups, ResourceId{Id: contentGroupId})
+	}
+
+	var userResponse UserContentGroupsApiResponse
+
+	response, rateLimit, err := c.doRestRequest(
+		ctx,
+		http.MethodPut,
+		c.baseUrl.JoinPath(fmt.Sprintf(setContentGroupsPath, userId)),
+		request,
+		&userResponse,
+	)
+	if err != nil {
+		return nil, rateLimit, err
+	}
+	defer response.Body.Close()
+
+	return &userResponse, rateLimit, nil
+}
@@ -0,0 +1,41 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+)
+
+// DeleteDelegation removes a delegation, ending the delegate's ability to act
+// on behalf of the delegator.
+// https://compass.coupa.com/en-us/products/product-documentation/integration-technical-documentation/the-coupa-core-api/resources/reference-data-resources/delegations-api-(delegations)
+func (c *Client) DeleteDelegation(
+	ctx context.Context,
+	delegationId int,
+) (
+	*v2.RateLimitDescription,
+	error,
+) {
+	err := c.Initialize(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var delegationResponse Delegation
+
+	response, rateLimit, err := c.doRestRequest(
+		ctx,
+		http.MethodDelete,
+		c.baseUrl.JoinPath(fmt.Sprintf(delegationPath, delegationId)),
+		nil,
+		&delegationResponse,
+	)
+	if err != nil {
+		return rateLimit, err
+	}
+	defer response.Body.Close()
+
+	return rateLimit, nil
+}
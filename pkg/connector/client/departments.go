@@ -0,0 +1,51 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+)
+
+// SetUserDepartment moves a user to a department. A nil departmentId removes
+// the user from their department.
+// https://compass.coupa.com/en-us/products/product-documentation/integration-technical-documentation/the-coupa-core-api/resources/reference-data-resources/users-api-(users)
+func (c *Client) SetUserDepartment(
+	ctx context.Context,
+	userId int,
+	departmentId *int,
+) (
+	*UserDepartment,
+	*v2.RateLimitDescription,
+	error,
+) {
+	err := c.Initialize(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	request := struct {
+		Department *ResourceId `json:"department"`
+	}{}
+
+	if departmentId != nil {
+		request.Department = &ResourceId{Id: *departmentId}
+	}
+
+	var userResponse UserDepartment
+
+	response, rateLimit, err := c.doRestRequest(
+		ctx,
+		http.MethodPut,
+		c.baseUrl.JoinPath(fmt.Sprintf(setDepartmentPath, userId)),
+		request,
+		&userResponse,
+	)
+	if err != nil {
+		return nil, rateLimit, err
+	}
+	defer response.Body.Close()
+
+	return &userResponse, rateLimit, nil
+}
@@ -0,0 +1,77 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+)
+
+// documentOwner is the REST path of a document type and the attribute of the
+// user the document is assigned to.
+type documentOwner struct {
+	path      string
+	attribute string
+}
+
+var documentOwners = map[DocumentType]documentOwner{
+	DocumentTypePendingApproval: {path: approvalPath, attribute: "approver"},
+	DocumentTypeRequisition:     {path: requisitionPath, attribute: "requested-by"},
+	DocumentTypeInvoice:         {path: invoicePath, attribute: "created-by"},
+	DocumentTypeContract:        {path: contractPath, attribute: "contract-owner"},
+}
+
+// ReassignDocument assigns an in-flight document to another user: the
+// approver of a pending approval, the requester of a requisition, the creator
+// of an invoice or the owner of a contract. It returns the user the document
+// is assigned to afterwards.
+func (c *Client) ReassignDocument(
+	ctx context.Context,
+	documentType DocumentType,
+	documentId int,
+	userId int,
+) (
+	*ResourceId,
+	*v2.RateLimitDescription,
+	error,
+) {
+	owner, ok := documentOwners[documentType]
+	if !ok {
+		return nil, nil, fmt.Errorf("baton-coupa: unknown document type %s", documentType)
+	}
+
+	err := c.Initialize(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	request := map[string]ResourceId{
+		owner.attribute: {Id: userId},
+	}
+
+	var documentResponse map[string]json.RawMessage
+
+	response, rateLimit, err := c.doRestRequest(
+		ctx,
+		http.MethodPut,
+		c.baseUrl.JoinPath(fmt.Sprintf(owner.path, documentId)),
+		request,
+		&documentResponse,
+	)
+	if err != nil {
+		return nil, rateLimit, err
+	}
+	defer response.Body.Close()
+
+	var ownerResponse *ResourceId
+	if attribute, ok := documentResponse[owner.attribute]; ok {
+		err = json.Unmarshal(attribute, &ownerResponse)
+		if err != nil {
+			return nil, rateLimit, err
+		}
+	}
+
+	return ownerResponse, rateLimit, nil
+}
@@ -0,0 +1,396 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/conductorone/baton-sdk/pkg/uhttp"
+)
+
+var journalUserPath = regexp.MustCompile(`^/api/users/(\d+)`)
+
+// JournalTask describes the connector operation a write was made for.
+type JournalTask struct {
+	Operation    string `json:"operation"`
+	ResourceType string `json:"resource_type,omitempty"`
+	ResourceId   string `json:"resource_id,omitempty"`
+	Entitlement  string `json:"entitlement,omitempty"`
+	Principal    string `json:"principal,omitempty"`
+}
+
+type journalTaskKey struct{}
+
+// WithJournalTask sets the operation the writes made with ctx are journaled
+// under.
+func WithJournalTask(ctx context.Context, task JournalTask) context.Context {
+	return context.WithValue(ctx, journalTaskKey{}, task)
+}
+
+func journalTaskFrom(ctx context.Context) JournalTask {
+	task, ok := ctx.Value(journalTaskKey{}).(JournalTask)
+	if !ok {
+		return JournalTask{Operation: "unknown"}
+	}
+	return task
+}
+
+// JournalEntry records a write made to Coupa. Before is the state of the
+// resource fetched right before the write, so the write can be reverted.
+// Each entry holds the hash of the previous one, so that editing, removing
+// or reordering entries breaks the chain. The hashes are HMACs with the
+// journal key, so that the chain cannot be recomputed without it.
+type JournalEntry struct {
+	Timestamp time.Time       `json:"timestamp"`
+	Task      JournalTask     `json:"task"`
+	UserId    string          `json:"user_id,omitempty"`
+	Method    string          `json:"method"`
+	Path      string          `json:"path"`
+	Before    json.RawMessage `json:"before,omitempty"`
+	Requested json.RawMessage `json:"requested,omitempty"`
+	Response  json.RawMessage `json:"response,omitempty"`
+	Error     string          `json:"error,omitempty"`
+	PrevHash  string          `json:"prev_hash"`
+	Hash      string          `json:"hash"`
+}
+
+func (e JournalEntry) computeHash(key []byte) (string, error) {
+	e.Hash = ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	return computeMac(key, data), nil
+}
+
+func computeMac(key []byte, data []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// JournalHead is the number of entries of a journal and the hash of the last
+// one, signed with the journal key. It is written next to the journal after
+// each entry, so that removing the last entries is detected. It can be
+// recorded elsewhere too, to detect a journal rolled back along with its head.
+type JournalHead struct {
+	Entries int    `json:"entries"`
+	Hash    string `json:"hash"`
+	Mac     string `json:"mac"`
+}
+
+func (h JournalHead) computeMac(key []byte) string {
+	return computeMac(key, []byte(fmt.Sprintf("%d:%s", h.Entries, h.Hash)))
+}
+
+func journalHeadPath(path string) string {
+	return path + ".head"
+}
+
+// Revertible tells whether the write can be reverted by putting back the
+// before state, which must hold every field the write changed.
+func (e JournalEntry) Revertible() bool {
+	if e.Method != http.MethodPut || len(e.Before) == 0 || e.Error != "" {
+		return false
+	}
+
+	var before, requested map[string]json.RawMessage
+	if json.Unmarshal(e.Before, &before) != nil || json.Unmarshal(e.Requested, &requested) != nil {
+		return false
+	}
+	// A before state that is an error response rather than the resource,
+	// which older journals can hold, must not be put back.
+	if _, ok := before["id"]; !ok {
+		return false
+	}
+	if _, ok := before["errors"]; ok {
+		return false
+	}
+	for field := range requested {
+		if _, ok := before[field]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+type journal struct {
+	mutex sync.Mutex
+	path  string
+	key   []byte
+	head  JournalHead
+}
+
+// openJournal opens the journal to append to it, after verifying the entries
+// already written.
+func openJournal(path string, key string) (*journal, error) {
+	if key == "" {
+		return nil, errors.New("baton-coupa: the journal needs a key")
+	}
+
+	j := &journal{path: path, key: []byte(key)}
+
+	entries, err := ReadJournal(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	head, err := ReadJournalHead(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	_, err = VerifyJournal(entries, head, key)
+	if err != nil {
+		return nil, err
+	}
+	if head != nil {
+		j.head = *head
+	}
+	return j, nil
+}
+
+// append chains the entry to the last one, writes it to the journal and
+// updates the head.
+func (j *journal) append(entry JournalEntry) (JournalHead, error) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	entry.PrevHash = j.head.Hash
+	hash, err := entry.computeHash(j.key)
+	if err != nil {
+		return JournalHead{}, err
+	}
+	entry.Hash = hash
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return JournalHead{}, err
+	}
+
+	file, err := os.OpenFile(j.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return JournalHead{}, fmt.Errorf("baton-coupa: opening journal: %w", err)
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(line, '\n'))
+	if err != nil {
+		return JournalHead{}, fmt.Errorf("baton-coupa: writing journal: %w", err)
+	}
+	err = file.Sync()
+	if err != nil {
+		return JournalHead{}, fmt.Errorf("baton-coupa: writing journal: %w", err)
+	}
+
+	head := JournalHead{Entries: j.head.Entries + 1, Hash: hash}
+	head.Mac = head.computeMac(j.key)
+	err = writeJournalHead(j.path, head)
+	if err != nil {
+		return JournalHead{}, err
+	}
+
+	j.head = head
+	return head, nil
+}
+
+// writeJournalHead replaces the head of the journal, through a rename so that
+// it is never left half written.
+func writeJournalHead(path string, head JournalHead) error {
+	data, err := json.Marshal(head)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".head-*")
+	if err != nil {
+		return fmt.Errorf("baton-coupa: writing journal head: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), journalHeadPath(path))
+	}
+	if err != nil {
+		return fmt.Errorf("baton-coupa: writing journal head: %w", err)
+	}
+	return nil
+}
+
+// ReadJournalHead reads the head written next to a journal.
+func ReadJournalHead(path string) (*JournalHead, error) {
+	data, err := os.ReadFile(journalHeadPath(path))
+	if err != nil {
+		return nil, err
+	}
+
+	var head JournalHead
+	err = json.Unmarshal(data, &head)
+	if err != nil {
+		return nil, fmt.Errorf("baton-coupa: journal head: %w", err)
+	}
+	return &head, nil
+}
+
+// ReadJournal reads the entries of a journal, without verifying them.
+func ReadJournal(path string) ([]JournalEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return readJournal(file)
+}
+
+func readJournal(reader io.Reader) ([]JournalEntry, error) {
+	entries := make([]JournalEntry, 0)
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry JournalEntry
+		err := json.Unmarshal(scanner.Bytes(), &entry)
+		if err != nil {
+			return nil, fmt.Errorf("baton-coupa: journal line %d: %w", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// VerifyJournal checks the hash chain of the entries with the journal key,
+// then that they end at the head, and returns the index of the first entry
+// that was tampered with. A journal with entries must have a head.
+func VerifyJournal(entries []JournalEntry, head *JournalHead, key string) (int, error) {
+	prevHash := ""
+	for i, entry := range entries {
+		if entry.PrevHash != prevHash {
+			return i, fmt.Errorf("baton-coupa: journal entry %d does not follow the previous entry", i)
+		}
+		hash, err := entry.computeHash([]byte(key))
+		if err != nil {
+			return i, err
+		}
+		if !hmac.Equal([]byte(hash), []byte(entry.Hash)) {
+			return i, fmt.Errorf("baton-coupa: journal entry %d was modified", i)
+		}
+		prevHash = entry.Hash
+	}
+
+	if head == nil {
+		if len(entries) == 0 {
+			return 0, nil
+		}
+		return 0, errors.New("baton-coupa: the journal has no head")
+	}
+	if !hmac.Equal([]byte(head.computeMac([]byte(key))), []byte(head.Mac)) {
+		return 0, errors.New("baton-coupa: the journal head was modified")
+	}
+	if head.Entries != len(entries) || head.Hash != prevHash {
+		index := min(head.Entries, len(entries))
+		return index, fmt.Errorf("baton-coupa: the journal has %d entries, its head %d", len(entries), head.Entries)
+	}
+	return len(entries), nil
+}
+
+// journalUserId returns the id of the user a write is about, from the users
+// API path or else from the task principal.
+func journalUserId(path string, task JournalTask) string {
+	match := journalUserPath.FindStringSubmatch(path)
+	if match != nil {
+		return match[1]
+	}
+	return task.Principal
+}
+
+// getState fetches the current state of the resource a write is about, with
+// the same fields as the write returns. Error responses are rejected, so that
+// they are never journaled as the state to revert to.
+func (c *Client) getState(ctx context.Context, resourceUrl *url.URL) (json.RawMessage, error) {
+	request, err := c.wrapper.NewRequest(
+		ctx,
+		http.MethodGet,
+		resourceUrl,
+		uhttp.WithAcceptJSONHeader(),
+		WithBearerToken(c.readWriteToken),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// The wrapper caches GET responses, which would return the state from
+	// before an earlier write to the same resource.
+	response, err := c.wrapper.HttpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return nil, fmt.Errorf("baton-coupa: fetching the state of %s: %s: %s", resourceUrl.Path, response.Status, string(body))
+	}
+	if !json.Valid(body) {
+		return nil, fmt.Errorf("baton-coupa: unexpected state of %s: %s", resourceUrl.Path, string(body))
+	}
+	return body, nil
+}
+
+// Revert puts back the before state of a journaled write.
+func (c *Client) Revert(ctx context.Context, entry JournalEntry) error {
+	if !entry.Revertible() {
+		return fmt.Errorf("baton-coupa: the %s %s write cannot be reverted", entry.Method, entry.Path)
+	}
+
+	err := c.Initialize(ctx)
+	if err != nil {
+		return err
+	}
+
+	ctx = WithJournalTask(ctx, JournalTask{Operation: "revert", ResourceId: entry.Hash})
+
+	var target json.RawMessage
+	response, _, err := c.doRestRequest(
+		ctx,
+		entry.Method,
+		c.baseUrl.JoinPath(entry.Path),
+		entry.Before,
+		&target,
+	)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	return nil
+}
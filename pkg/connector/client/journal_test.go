@@ -0,0 +1,117 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJournal(t *testing.T) {
+	const key = "secret"
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+
+	_, err := openJournal(path, "")
+	require.Error(t, err)
+
+	j, err := openJournal(path, key)
+	require.NoError(t, err)
+	for _, roles := range []string{`[{"id":1}]`, `[{"id":1},{"id":2}]`} {
+		_, err = j.append(JournalEntry{
+			Method:    "PUT",
+			Path:      "/api/users/7",
+			Before:    json.RawMessage(`{"id":7,"roles":[]}`),
+			Requested: json.RawMessage(`{"roles":` + roles + `}`),
+		})
+		require.NoError(t, err)
+	}
+
+	// A reopened journal continues the chain.
+	j, err = openJournal(path, key)
+	require.NoError(t, err)
+	head, err := j.append(JournalEntry{Method: "DELETE", Path: "/api/delegations/3"})
+	require.NoError(t, err)
+	require.Equal(t, 3, head.Entries)
+
+	entries, err := ReadJournal(path)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	require.Equal(t, entries[2].Hash, head.Hash)
+	readHead, err := ReadJournalHead(path)
+	require.NoError(t, err)
+	require.Equal(t, head, *readHead)
+	verified, err := VerifyJournal(entries, readHead, key)
+	require.NoError(t, err)
+	require.Equal(t, 3, verified)
+	require.True(t, entries[0].Revertible())
+	require.False(t, entries[2].Revertible())
+
+	// The before state must hold the fields the write changed.
+	approval := JournalEntry{
+		Method:    "PUT",
+		Path:      "/api/approvals/3",
+		Before:    json.RawMessage(`{"id":3}`),
+		Requested: json.RawMessage(`{"approver":{"id":9}}`),
+	}
+	require.False(t, approval.Revertible())
+	approval.Before = json.RawMessage(`{"id":3,"approver":{"id":7}}`)
+	require.True(t, approval.Revertible())
+
+	// An error response is never put back.
+	approval.Before = json.RawMessage(`{"errors":{"approver":["is invalid"]},"approver":null}`)
+	require.False(t, approval.Revertible())
+
+	t.Run("other key", func(t *testing.T) {
+		index, err := VerifyJournal(entries, readHead, "other")
+		require.Error(t, err)
+		require.Equal(t, 0, index)
+	})
+
+	t.Run("modified entry", func(t *testing.T) {
+		modified := append([]JournalEntry{}, entries...)
+		modified[1].Requested = json.RawMessage(`{"roles":[]}`)
+		index, err := VerifyJournal(modified, readHead, key)
+		require.Error(t, err)
+		require.Equal(t, 1, index)
+	})
+
+	t.Run("removed entry", func(t *testing.T) {
+		removed := []JournalEntry{entries[0], entries[2]}
+		index, err := VerifyJournal(removed, readHead, key)
+		require.Error(t, err)
+		require.Equal(t, 1, index)
+	})
+
+	t.Run("removed last entry", func(t *testing.T) {
+		index, err := VerifyJournal(entries[:2], readHead, key)
+		require.Error(t, err)
+		require.Equal(t, 2, index)
+
+		forged := JournalHead{Entries: 2, Hash: entries[1].Hash, Mac: readHead.Mac}
+		_, err = VerifyJournal(entries[:2], &forged, key)
+		require.Error(t, err)
+
+		_, err = VerifyJournal(entries[:2], nil, key)
+		require.Error(t, err)
+	})
+
+	t.Run("tampered journal is not appended to", func(t *testing.T) {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		lines := bytes.SplitAfter(data, []byte("\n"))
+		truncated := filepath.Join(t.TempDir(), "journal.jsonl")
+		require.NoError(t, os.WriteFile(truncated, bytes.Join(lines[:2], nil), 0o600))
+		require.NoError(t, writeJournalHead(truncated, head))
+
+		_, err = openJournal(truncated, key)
+		require.Error(t, err)
+	})
+}
+
+func TestJournalUserId(t *testing.T) {
+	require.Equal(t, "7", journalUserId(`/api/users/7?fields=["id"]`, JournalTask{Principal: "8"}))
+	require.Equal(t, "8", journalUserId("/api/approval_groups/3", JournalTask{Principal: "8"}))
+}
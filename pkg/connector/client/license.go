@@ -2,8 +2,10 @@ package client
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"slices"
 )
 
 // SetLicense sets the roles for a user.
@@ -13,23 +15,30 @@ func (c *Client) SetLicense(
 	userId int,
 	licenseId string,
 	active bool,
+) (*UserLicenseResponse, error) {
+	return c.SetLicenses(ctx, userId, map[string]bool{
+		licenseId: active,
+	})
+}
+
+// SetLicenses sets several licenses of a user at once.
+func (c *Client) SetLicenses(
+	ctx context.Context,
+	userId int,
+	licenses map[string]bool,
 ) (*UserLicenseResponse, error) {
 	err := c.Initialize(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	request := map[string]bool{
-		licenseId: active,
-	}
-
 	var userResponse UserLicenseResponse
 
 	resonse, _, err := c.doRestRequest(
 		ctx,
 		http.MethodPut,
 		c.baseUrl.JoinPath(fmt.Sprintf(setLicensePath, userId)),
-		request,
+		licenses,
 		&userResponse,
 	)
 
@@ -41,3 +50,26 @@ func (c *Client) SetLicense(
 
 	return &userResponse, nil
 }
+
+// Assigned returns the ids of the licenses set on the user.
+func (r *UserLicenseResponse) Assigned() ([]string, error) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var flags map[string]interface{}
+	err = json.Unmarshal(data, &flags)
+	if err != nil {
+		return nil, err
+	}
+
+	licenseIds := make([]string, 0)
+	for licenseId, assigned := range flags {
+		if assigned == true {
+			licenseIds = append(licenseIds, licenseId)
+		}
+	}
+	slices.Sort(licenseIds)
+	return licenseIds, nil
+}
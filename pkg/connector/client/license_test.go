@@ -0,0 +1,17 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserLicenseResponseAssigned(t *testing.T) {
+	assigned, err := (&UserLicenseResponse{Id: 7}).Assigned()
+	require.NoError(t, err)
+	require.Empty(t, assigned)
+
+	assigned, err = (&UserLicenseResponse{Id: 7, PurchasingUser: true, SpendGuardUser: true}).Assigned()
+	require.NoError(t, err)
+	require.Equal(t, []string{"purchasing-user", "spend-guard-user"}, assigned)
+}
@@ -1,5 +1,7 @@
 package client
 
+import "encoding/json"
+
 type ResourceId struct {
 	Id int `json:"id"`
 }
@@ -17,6 +19,67 @@ type GroupsQueryResponse struct {
 	UserGroups []*Group `json:"userGroups"`
 }
 
+type BusinessEntitiesQueryResponse struct {
+	BusinessEntities []*BusinessEntity `json:"businessEntities"`
+}
+
+type SuppliersQueryResponse struct {
+	Suppliers []*Supplier `json:"suppliers"`
+}
+
+type SupplierUsersQueryResponse struct {
+	SupplierUsers []*SupplierUser `json:"supplierUsers"`
+}
+
+type OAuthClientsQueryResponse struct {
+	OAuthClients []*OAuthClient `json:"oauthClients"`
+}
+
+type ApiKeysQueryResponse struct {
+	ApiKeys []*ApiKey `json:"apiKeys"`
+}
+
+type PcardsQueryResponse struct {
+	Pcards []*Pcard `json:"pcards"`
+}
+
+type WarehousesQueryResponse struct {
+	Warehouses []*Warehouse `json:"warehouses"`
+}
+
+type WarehouseGrantsQueryResponse struct {
+	Users []struct {
+		Id int `json:"id"`
+	} `json:"users"`
+}
+
+type BudgetLinesQueryResponse struct {
+	BudgetLines []*BudgetLine `json:"budgetLines"`
+}
+
+type BudgetLineOwnersQueryResponse struct {
+	BudgetLines []struct {
+		Id     int `json:"id"`
+		Owners []struct {
+			Id int `json:"id"`
+		} `json:"owners"`
+	} `json:"budgetLines"`
+}
+
+type DepartmentsQueryResponse struct {
+	Departments []*Department `json:"departments"`
+}
+
+type DepartmentGrantsQueryResponse struct {
+	Users []struct {
+		Id int `json:"id"`
+	} `json:"users"`
+}
+
+type DocumentsQueryResponse struct {
+	Documents []*Document `json:"documents"`
+}
+
 type RolesQueryResponse struct {
 	Roles []*Role `json:"roles"`
 }
@@ -31,6 +94,44 @@ type GroupMembersQueryResponse struct {
 	} `json:"userGroups"`
 }
 
+type ApprovalGroupsQueryResponse struct {
+	ApprovalGroups []*ApprovalGroup `json:"approvalGroups"`
+}
+
+type ApprovalGroupMembersQueryResponse struct {
+	ApprovalGroups []struct {
+		Id    int `json:"id"`
+		Users []struct {
+			Id int `json:"id"`
+		} `json:"users"`
+	} `json:"approvalGroups"`
+}
+
+type ApprovalLimitsQueryResponse struct {
+	ApprovalLimits []*ApprovalLimit `json:"approvalLimits"`
+}
+
+type ApprovalLimitGrantsQueryResponse struct {
+	Users []struct {
+		Id int `json:"id"`
+	} `json:"users"`
+}
+
+type DelegationsQueryResponse struct {
+	Delegations []*Delegation `json:"delegations"`
+}
+
+type ApprovalChainsQueryResponse struct {
+	ApprovalChains []*ApprovalChain `json:"approvalChains"`
+}
+
+type ApprovalChainApproversQueryResponse struct {
+	ApprovalChains []struct {
+		Id        int                     `json:"id"`
+		Approvers []ApprovalChainApprover `json:"approvers"`
+	} `json:"approvalChains"`
+}
+
 type RoleGrantsQueryResponse struct {
 	Users []struct {
 		Id int `json:"id"`
@@ -44,24 +145,161 @@ type LicenseGrantsQueryResponse struct {
 }
 
 type User struct {
-	ID       int    `json:"id"`
-	Email    string `json:"email"`
-	Fullname string `json:"fullname"`
-	Active   bool   `json:"active"`
+	ID               int          `json:"id"`
+	Email            string       `json:"email"`
+	Fullname         string       `json:"fullname"`
+	Active           bool         `json:"active"`
+	BusinessEntities []ResourceId `json:"businessEntities,omitempty"`
 }
 
 type Group struct {
+	ID               int          `json:"id"`
+	Name             string       `json:"name"`
+	Description      *string      `json:"description,omitempty"`
+	BusinessEntities []ResourceId `json:"businessEntities,omitempty"`
+}
+
+type BusinessEntity struct {
+	ID          int     `json:"id"`
+	Name        string  `json:"name"`
+	Description *string `json:"description,omitempty"`
+	Active      bool    `json:"active"`
+}
+
+type Supplier struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+	Status      string `json:"status"`
+}
+
+// SupplierUser is a user of the Coupa Supplier Portal (CSP).
+type SupplierUser struct {
+	ID          int     `json:"id"`
+	Email       string  `json:"email"`
+	FirstName   string  `json:"firstName"`
+	LastName    string  `json:"lastName"`
+	Active      bool    `json:"active"`
+	LastLoginAt *string `json:"lastLoginAt,omitempty"`
+}
+
+// OAuthClient is an OAuth2/OpenID Connect client registered in the Coupa
+// instance, used by integrations to call the API.
+type OAuthClient struct {
+	ID        int      `json:"id"`
+	Name      string   `json:"name"`
+	ClientId  string   `json:"clientId"`
+	GrantType string   `json:"grantType"`
+	Active    bool     `json:"active"`
+	Scopes    []string `json:"scopes"`
+}
+
+// ApiKey is a legacy Coupa API key. The key itself is never requested.
+type ApiKey struct {
+	ID          int      `json:"id"`
+	Name        string   `json:"name"`
+	Active      bool     `json:"active"`
+	CreatedAt   *string  `json:"createdAt,omitempty"`
+	LastUsedAt  *string  `json:"lastUsedAt,omitempty"`
+	CreatedBy   *Creator `json:"createdBy,omitempty"`
+	Permissions []string `json:"permissions"`
+}
+
+type Creator struct {
+	Id    int    `json:"id"`
+	Login string `json:"login"`
+}
+
+// Pcard is a Coupa corporate purchasing card. Coupa only returns the masked
+// card number.
+type Pcard struct {
+	ID       int         `json:"id"`
+	Name     string      `json:"name"`
+	Number   string      `json:"number"`
+	Expiry   string      `json:"expiry"`
+	Active   bool        `json:"active"`
+	CardType string      `json:"cardType"`
+	Limit    *string     `json:"limit,omitempty"`
+	Currency *Currency   `json:"currency,omitempty"`
+	User     *ResourceId `json:"user,omitempty"`
+}
+
+type Warehouse struct {
 	ID          int     `json:"id"`
 	Name        string  `json:"name"`
 	Description *string `json:"description,omitempty"`
+	Active      bool    `json:"active"`
+}
+
+type BudgetLine struct {
+	ID          int       `json:"id"`
+	Code        string    `json:"code"`
+	Description *string   `json:"description,omitempty"`
+	Amount      string    `json:"amount"`
+	Currency    *Currency `json:"currency,omitempty"`
+	Period      *struct {
+		Name string `json:"name"`
+	} `json:"period,omitempty"`
+}
+
+type Department struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	Active bool   `json:"active"`
+}
+
+// Document is an in-flight approval, requisition, invoice or contract and the
+// user it is assigned to.
+type Document struct {
+	ID    int         `json:"id"`
+	Owner *ResourceId `json:"owner,omitempty"`
 }
 
 type Role struct {
+	Name        string   `json:"name"`
+	ID          int      `json:"id"`
+	Description *string  `json:"description,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+}
+
+type ApprovalGroup struct {
+	ID          int     `json:"id"`
 	Name        string  `json:"name"`
+	Description *string `json:"description,omitempty"`
+}
+
+type ApprovalChain struct {
 	ID          int     `json:"id"`
+	Name        string  `json:"name"`
 	Description *string `json:"description,omitempty"`
 }
 
+// ApprovalChainApprover is a single approver of an approval chain. The
+// approver is either a user or an approval group.
+type ApprovalChainApprover struct {
+	ApproverId   int    `json:"approverId"`
+	ApproverType string `json:"approverType"`
+}
+
+type Currency struct {
+	Code string `json:"code"`
+}
+
+type ApprovalLimit struct {
+	ID       int       `json:"id"`
+	Name     string    `json:"name"`
+	Amount   string    `json:"amount"`
+	Currency *Currency `json:"currency,omitempty"`
+}
+
+type Delegation struct {
+	ID        int        `json:"id"`
+	StartDate string     `json:"startDate"`
+	EndDate   string     `json:"endDate"`
+	Delegator ResourceId `json:"delegator"`
+	Delegate  ResourceId `json:"delegate"`
+}
+
 type License struct {
 	Name        string
 	ID          string
@@ -86,6 +324,83 @@ type UserGroupsResponse struct {
 	Users []UserGroups `json:"users"`
 }
 
+// ContentGroup is a Coupa content group, which restricts the data a user can
+// see to some business groups.
+type ContentGroup struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type UserContentGroups struct {
+	Id            int            `json:"id"`
+	ContentGroups []ContentGroup `json:"contentGroups"`
+}
+
+type UserContentGroupsResponse struct {
+	Users []UserContentGroups `json:"users"`
+}
+
+type UserContentGroupsApiResponse struct {
+	Id            int            `json:"id"`
+	ContentGroups []ContentGroup `json:"content-groups"`
+}
+
+type UserApprovalLimits struct {
+	Id                       int         `json:"id"`
+	RequisitionApprovalLimit *ResourceId `json:"requisitionApprovalLimit"`
+	ExpenseApprovalLimit     *ResourceId `json:"expenseApprovalLimit"`
+}
+
+type UserApprovalLimitsResponse struct {
+	Users []UserApprovalLimits `json:"users"`
+}
+
+type UserApprovalLimitsApiResponse struct {
+	Id                       int         `json:"id"`
+	RequisitionApprovalLimit *ResourceId `json:"requisition-approval-limit"`
+	ExpenseApprovalLimit     *ResourceId `json:"expense-approval-limit"`
+}
+
+type UserWarehouses struct {
+	Id         int          `json:"id"`
+	Warehouses []ResourceId `json:"warehouses"`
+}
+
+type UserWarehousesResponse struct {
+	Users []UserWarehouses `json:"users"`
+}
+
+type UserDepartment struct {
+	Id         int         `json:"id"`
+	Department *ResourceId `json:"department"`
+}
+
+type UserDepartmentResponse struct {
+	Users []UserDepartment `json:"users"`
+}
+
+type UserActiveResponse struct {
+	Id     int  `json:"id"`
+	Active bool `json:"active"`
+}
+
+type PcardActiveResponse struct {
+	Id     int  `json:"id"`
+	Active bool `json:"active"`
+}
+
+// UserLicensesResponse holds the license flags of users, keyed by the
+// GraphQL name of the flag.
+// UsersFieldsResponse holds users queried with UsersFieldsQuery, each is
+// decoded by the caller since the fields vary.
+type UsersFieldsResponse struct {
+	Users []json.RawMessage `json:"users"`
+}
+
+type UserLicensesResponse struct {
+	Users []map[string]interface{} `json:"users"`
+}
+
 type UserGroupsApiResponse struct {
 	Id    int     `json:"id"`
 	Group []Group `json:"user-groups"`
@@ -112,3 +427,8 @@ type UserLicenseResponse struct {
 	TravelUser      bool `json:"travel-user"`
 	TreasuryUser    bool `json:"treasury-user"`
 }
+
+type ApprovalGroupApiResponse struct {
+	Id    int          `json:"id"`
+	Users []ResourceId `json:"users"`
+}
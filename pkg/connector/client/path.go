@@ -3,12 +3,49 @@ package client
 const (
 	apiPathAuth  = "/oauth2/token"
 	apiPathQuery = "/api/graphql"
+	apiPathUsers = "/api/users"
 
 	// setRolesPath set user id in the path.
 	setRolesPath = `/api/users/%d?fields=["id",{"roles":["id","description","name"]}]`
 	// setGroupPath set user id in the path.
 	setGroupPath = `/api/users/%d?fields=["id",{"user_groups":["id","name","description"]}]`
 
+	// setContentGroupsPath set user id in the path.
+	setContentGroupsPath = `/api/users/%d?fields=["id",{"content_groups":["id","name"]}]`
+
+	// setApprovalGroupUsersPath set approval group id in the path.
+	setApprovalGroupUsersPath = `/api/approval_groups/%d?fields=["id",{"users":["id"]}]`
+
+	// setApprovalLimitsPath set user id in the path.
+	setApprovalLimitsPath = `/api/users/%d?fields=["id",{"requisition_approval_limit":["id"]},{"expense_approval_limit":["id"]}]`
+
+	// delegationPath set delegation id in the path.
+	delegationPath = `/api/delegations/%d`
+
+	// setWarehousesPath set user id in the path.
+	setWarehousesPath = `/api/users/%d?fields=["id",{"warehouses":["id"]}]`
+
+	// setDepartmentPath set user id in the path.
+	setDepartmentPath = `/api/users/%d?fields=["id",{"department":["id"]}]`
+
+	// setUserActivePath set user id in the path.
+	setUserActivePath = `/api/users/%d?fields=["id","active"]`
+
+	// setPcardActivePath set p-card id in the path.
+	setPcardActivePath = `/api/pcards/%d?fields=["id","active"]`
+
+	// approvalPath set approval id in the path.
+	approvalPath = `/api/approvals/%d?fields=["id",{"approver":["id"]}]`
+
+	// requisitionPath set requisition id in the path.
+	requisitionPath = `/api/requisitions/%d?fields=["id",{"requested_by":["id"]}]`
+
+	// invoicePath set invoice id in the path.
+	invoicePath = `/api/invoices/%d?fields=["id",{"created_by":["id"]}]`
+
+	// contractPath set contract id in the path.
+	contractPath = `/api/contracts/%d?fields=["id",{"contract_owner":["id"]}]`
+
 	// setLicensePath set user id in the path.
 	setLicensePath = `/api/users/%d?fields=["id","analyticsUser","aicUser","ccwUser","contractsUser","expenseUser","inventoryUser","purchasingUser","riskAssessUser","sourcingUser","spendGuardUser","supplyChainUser","travelUser","treasuryUser"]`
 )
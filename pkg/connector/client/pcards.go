@@ -0,0 +1,47 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+)
+
+// SetPcardActive activates or deactivates a p-card.
+func (c *Client) SetPcardActive(
+	ctx context.Context,
+	pcardId int,
+	active bool,
+) (
+	*PcardActiveResponse,
+	*v2.RateLimitDescription,
+	error,
+) {
+	err := c.Initialize(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	request := struct {
+		Active bool `json:"active"`
+	}{
+		Active: active,
+	}
+
+	var pcardResponse PcardActiveResponse
+
+	response, rateLimit, err := c.doRestRequest(
+		ctx,
+		http.MethodPut,
+		c.baseUrl.JoinPath(fmt.Sprintf(setPcardActivePath, pcardId)),
+		request,
+		&pcardResponse,
+	)
+	if err != nil {
+		return nil, rateLimit, err
+	}
+	defer response.Body.Close()
+
+	return &pcardResponse, rateLimit, nil
+}
@@ -1,6 +1,12 @@
 package client
 
-import "fmt"
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
 
 const (
 	getAllUsersQuery = `query getUsers{
@@ -9,6 +15,17 @@ const (
 		email
 		fullname
 		active
+		businessEntities { id }
+	}
+}`
+
+	getBusinessEntityUsersQuery = `query getUsers{
+	users(query: "business_entities[id]=%s&type[blank]=true%s") {
+		id
+		email
+		fullname
+		active
+		businessEntities { id }
 	}
 }`
 
@@ -17,6 +34,197 @@ const (
 		id
 		name
 		description
+		businessEntities { id }
+	}
+}`
+
+	getBusinessEntityGroupsQuery = `query getGroups {
+	userGroups(query: "business_entities[id]=%s%s") {
+		id
+		name
+		description
+		businessEntities { id }
+	}
+}`
+
+	getBusinessEntitiesQuery = `query getBusinessEntities {
+	businessEntities(query: "%s") {
+		id
+		name
+		description
+		active
+	}
+}`
+
+	getSuppliersQuery = `query getSuppliers {
+	suppliers(query: "%s") {
+		id
+		name
+		displayName
+		status
+	}
+}`
+
+	getSupplierUsersQuery = `query getSupplierUsers {
+	supplierUsers(query: "supplier[id]=%s%s") {
+		id
+		email
+		firstName
+		lastName
+		active
+		lastLoginAt
+	}
+}`
+
+	getOAuthClientsQuery = `query getOAuthClients {
+	oauthClients(query: "%s") {
+		id
+		name
+		clientId
+		grantType
+		active
+		scopes
+	}
+}`
+
+	getApiKeysQuery = `query getApiKeys {
+	apiKeys(query: "%s") {
+		id
+		name
+		active
+		createdAt
+		lastUsedAt
+		createdBy {
+			id
+			login
+		}
+		permissions
+	}
+}`
+
+	getPcardsQuery = `query getPcards {
+	pcards(query: "%s") {
+		id
+		name
+		number
+		expiry
+		active
+		cardType
+		limit
+		currency {
+			code
+		}
+		user {
+			id
+		}
+	}
+}`
+
+	getWarehousesQuery = `query getWarehouses {
+	warehouses(query: "%s") {
+		id
+		name
+		description
+		active
+	}
+}`
+
+	getWarehouseGrantListQuery = `query getWarehouseGrants {
+	users(query: "warehouses[id]=%s%s") {
+		id
+	}
+}`
+
+	getUserWarehouses = `query getUsers {
+	users(query: "id=%d") {
+		id warehouses { id name }
+	}
+}`
+
+	getBudgetLinesQuery = `query getBudgetLines {
+	budgetLines(query: "%s") {
+		id
+		code
+		description
+		amount
+		currency {
+			code
+		}
+		period {
+			name
+		}
+	}
+}`
+
+	getBudgetLineOwnersQuery = `query getBudgetLineOwners {
+	budgetLines(query: "id=%s") {
+		id
+		owners {
+			id
+		}
+	}
+}`
+
+	getDepartmentsQuery = `query getDepartments {
+	departments(query: "%s") {
+		id
+		name
+		active
+	}
+}`
+
+	getDepartmentGrantListQuery = `query getDepartmentGrants {
+	users(query: "department[id]=%s%s") {
+		id
+	}
+}`
+
+	getUserDepartment = `query getUsers {
+	users(query: "id=%d") {
+		id department { id }
+	}
+}`
+
+	getPendingApprovalsQuery = `query getPendingApprovals {
+	documents: approvals(query: "status=pending_approval&approver_type=User%s") {
+		id
+		owner: approver { id }
+	}
+}`
+
+	getOpenRequisitionsQuery = `query getOpenRequisitions {
+	documents: requisitions(query: "status[in]=draft,pending_approval,pending_buyer_action%s") {
+		id
+		owner: requestedBy { id }
+	}
+}`
+
+	getOpenInvoicesQuery = `query getOpenInvoices {
+	documents: invoices(query: "status[in]=draft,pending_approval,on_hold%s") {
+		id
+		owner: createdBy { id }
+	}
+}`
+
+	getOpenContractsQuery = `query getOpenContracts {
+	documents: contracts(query: "status[in]=draft,pending_approval,published%s") {
+		id
+		owner: contractOwner { id }
+	}
+}`
+
+	getUserStatus = `query getUsers {
+	users(query: "id=%d") {
+		id
+		email
+		fullname
+		active
+	}
+}`
+
+	getUserLicenses = `query getUsers {
+	users(query: "id=%d") {
+		id %s
 	}
 }`
 
@@ -36,18 +244,39 @@ const (
 	}
 }`
 
+	getRoleWithPermissionsQuery = `query getRoles {
+	roles(query: "%s") {
+		id
+		name
+		description
+		permissions
+	}
+}`
+
 	getRoleGrantListQuery = `query getRoleGrants {
 	users(query: "roles[id]=%s%s") {
 		id
 	}
 }`
 
+	getActiveRoleHoldersQuery = `query getActiveRoleHolders {
+	users(query: "roles[id]=%s&active=true%s") {
+		id
+	}
+}`
+
 	getLicenseGrantListQuery = `query getRoleGrants {
 	users(query: "%s=true%s") {
 		id
 	}
 }`
 
+	getUsersFieldsQuery = `query getUsers {
+	users(query: "id[in]=%s") {
+		id %s
+	}
+}`
+
 	getUserRoles = `query getUsers {
 	users(query: "id=%d") { 
 		id roles { id name description }
@@ -61,6 +290,91 @@ const (
 	}
 }
 `
+
+	getUserContentGroups = `query getUsers {
+	users(query: "id=%d") {
+		id contentGroups { id name }
+	}
+}`
+
+	getApprovalGroupsQuery = `query getApprovalGroups {
+	approvalGroups(query: "%s") {
+		id
+		name
+		description
+	}
+}`
+
+	getApprovalGroupMemberListQuery = `query getApprovalGroupMembers {
+	approvalGroups(query: "id=%s") {
+		id
+		users {
+			id
+		}
+	}
+}`
+
+	getUserApprovalGroupsQuery = `query getUserApprovalGroups {
+	approvalGroups(query: "users[id]=%d%s") {
+		id
+		users {
+			id
+		}
+	}
+}`
+
+	getApprovalLimitsQuery = `query getApprovalLimits {
+	approvalLimits(query: "%s") {
+		id
+		name
+		amount
+		currency {
+			code
+		}
+	}
+}`
+
+	getApprovalLimitGrantListQuery = `query getApprovalLimitGrants {
+	users(query: "%s[id]=%s%s") {
+		id
+	}
+}`
+
+	getUserApprovalLimits = `query getUsers {
+	users(query: "id=%d") {
+		id
+		requisitionApprovalLimit { id }
+		expenseApprovalLimit { id }
+	}
+}`
+
+	getDelegationsQuery = `query getDelegations {
+	delegations(query: "end_date[gt]=%s&%s%s") {
+		id
+		startDate
+		endDate
+		delegator { id }
+		delegate { id }
+	}
+}`
+
+	getApprovalChainsQuery = `query getApprovalChains {
+	approvalChains(query: "%s") {
+		id
+		name
+		description
+	}
+}`
+
+	getApprovalChainApproverListQuery = `query getApprovalChainApprovers {
+	approvalChains(query: "id=%s") {
+		id
+		approvers {
+			approverId
+			approverType
+		}
+	}
+}`
 )
 
 func pagination(pg string) string {
@@ -82,10 +396,135 @@ func AllUsersQuery(pg string) string {
 	return fmt.Sprintf(getAllUsersQuery, pagination(pg))
 }
 
+func BusinessEntityUsersQuery(businessEntityID string, pg string) string {
+	return fmt.Sprintf(getBusinessEntityUsersQuery, businessEntityID, appendedPagination(pg))
+}
+
 func GroupsQuery(pg string) string {
 	return fmt.Sprintf(getGroupsQuery, pagination(pg))
 }
 
+func BusinessEntityGroupsQuery(businessEntityID string, pg string) string {
+	return fmt.Sprintf(getBusinessEntityGroupsQuery, businessEntityID, appendedPagination(pg))
+}
+
+func BusinessEntitiesQuery(pg string) string {
+	return fmt.Sprintf(getBusinessEntitiesQuery, pagination(pg))
+}
+
+func SuppliersQuery(pg string) string {
+	return fmt.Sprintf(getSuppliersQuery, pagination(pg))
+}
+
+func SupplierUsersQuery(supplierID string, pg string) string {
+	return fmt.Sprintf(getSupplierUsersQuery, supplierID, appendedPagination(pg))
+}
+
+func OAuthClientsQuery(pg string) string {
+	return fmt.Sprintf(getOAuthClientsQuery, pagination(pg))
+}
+
+func ApiKeysQuery(pg string) string {
+	return fmt.Sprintf(getApiKeysQuery, pagination(pg))
+}
+
+func PcardsQuery(pg string) string {
+	return fmt.Sprintf(getPcardsQuery, pagination(pg))
+}
+
+func PcardQuery(pcardID string) string {
+	return fmt.Sprintf(getPcardsQuery, fmt.Sprintf("id=%s", pcardID))
+}
+
+func WarehousesQuery(pg string) string {
+	return fmt.Sprintf(getWarehousesQuery, pagination(pg))
+}
+
+func WarehouseGrantQuery(warehouseID string, pg string) string {
+	return fmt.Sprintf(getWarehouseGrantListQuery, warehouseID, appendedPagination(pg))
+}
+
+func GetUserWarehouses(userId int) string {
+	return fmt.Sprintf(getUserWarehouses, userId)
+}
+
+// BudgetLinesQuery lists budget lines, only the ones of the given budget
+// period when it is set. The period comes from the configuration, so it is
+// escaped to stay a single filter value inside the query string.
+func BudgetLinesQuery(period string, pg string) string {
+	filters := make([]string, 0)
+	if period != "" {
+		filters = append(filters, fmt.Sprintf("period[name]=%s", url.QueryEscape(period)))
+	}
+	if pg != "" {
+		filters = append(filters, pagination(pg))
+	}
+	return fmt.Sprintf(getBudgetLinesQuery, strings.Join(filters, "&"))
+}
+
+func BudgetLineOwnersQuery(budgetLineID string) string {
+	return fmt.Sprintf(getBudgetLineOwnersQuery, budgetLineID)
+}
+
+func DepartmentsQuery(pg string) string {
+	return fmt.Sprintf(getDepartmentsQuery, pagination(pg))
+}
+
+func DepartmentGrantQuery(departmentID string, pg string) string {
+	return fmt.Sprintf(getDepartmentGrantListQuery, departmentID, appendedPagination(pg))
+}
+
+func GetUserDepartment(userId int) string {
+	return fmt.Sprintf(getUserDepartment, userId)
+}
+
+// DocumentType is a kind of in-flight Coupa document that is assigned to a
+// user and gets stuck when the user is deactivated.
+type DocumentType string
+
+const (
+	DocumentTypePendingApproval DocumentType = "pending_approvals"
+	DocumentTypeRequisition     DocumentType = "open_requisitions"
+	DocumentTypeInvoice         DocumentType = "open_invoices"
+	DocumentTypeContract        DocumentType = "open_contracts"
+)
+
+var DocumentTypes = []DocumentType{
+	DocumentTypePendingApproval,
+	DocumentTypeRequisition,
+	DocumentTypeInvoice,
+	DocumentTypeContract,
+}
+
+var documentQueries = map[DocumentType]string{
+	DocumentTypePendingApproval: getPendingApprovalsQuery,
+	DocumentTypeRequisition:     getOpenRequisitionsQuery,
+	DocumentTypeInvoice:         getOpenInvoicesQuery,
+	DocumentTypeContract:        getOpenContractsQuery,
+}
+
+// documentOwnerFilters is the query filter on the user a document is
+// assigned to, for each document type.
+var documentOwnerFilters = map[DocumentType]string{
+	DocumentTypePendingApproval: "approver[id]",
+	DocumentTypeRequisition:     "requested_by[id]",
+	DocumentTypeInvoice:         "created_by[id]",
+	DocumentTypeContract:        "contract_owner[id]",
+}
+
+// DocumentsQuery lists the in-flight documents of a type across all users.
+func DocumentsQuery(documentType DocumentType, pg string) string {
+	return fmt.Sprintf(documentQueries[documentType], appendedPagination(pg))
+}
+
+// UserDocumentsQuery lists the in-flight documents of a type assigned to a user.
+func UserDocumentsQuery(documentType DocumentType, userId int, pg string) string {
+	return fmt.Sprintf(
+		documentQueries[documentType],
+		fmt.Sprintf("&%s=%d%s", documentOwnerFilters[documentType], userId, appendedPagination(pg)),
+	)
+}
+
 func GroupMembersQuery(groupID string) string {
 	return fmt.Sprintf(getGroupMemberListQuery, groupID)
 }
@@ -94,14 +533,46 @@ func RolesQuery(pg string) string {
 	return fmt.Sprintf(getRoleQuery, pagination(pg))
 }
 
+// RoleQuery queries a single role.
+func RoleQuery(roleID int) string {
+	return fmt.Sprintf(getRoleQuery, fmt.Sprintf("id=%d", roleID))
+}
+
+func RolesWithPermissionsQuery(pg string) string {
+	return fmt.Sprintf(getRoleWithPermissionsQuery, pagination(pg))
+}
+
 func RoleGrantQuery(roleID string, pg string) string {
 	return fmt.Sprintf(getRoleGrantListQuery, roleID, appendedPagination(pg))
 }
 
+func ActiveRoleHoldersQuery(roleID string, pg string) string {
+	return fmt.Sprintf(getActiveRoleHoldersQuery, roleID, appendedPagination(pg))
+}
+
 func LicenseGrantQuery(licenseName string, pg string) string {
 	return fmt.Sprintf(getLicenseGrantListQuery, licenseName, appendedPagination(pg))
 }
 
+func GetUser(userId int) string {
+	return fmt.Sprintf(getUserStatus, userId)
+}
+
+// GetUserLicenses queries the license flags of a user, licenseFields are the
+// GraphQL names of the flags.
+func GetUserLicenses(userId int, licenseFields []string) string {
+	return fmt.Sprintf(getUserLicenses, userId, strings.Join(licenseFields, " "))
+}
+
+// UsersFieldsQuery queries the given fields of several users at once.
+func UsersFieldsQuery(userIds []int, fields []string) string {
+	ids := make([]string, 0, len(userIds))
+	for _, userId := range userIds {
+		ids = append(ids, strconv.Itoa(userId))
+	}
+	return fmt.Sprintf(getUsersFieldsQuery, strings.Join(ids, ","), strings.Join(fields, " "))
+}
+
 func GetUserRoles(userId int) string {
 	return fmt.Sprintf(getUserRoles, userId)
 }
@@ -109,3 +580,80 @@ func GetUserRoles(userId int) string {
 func GetUserGroups(userId int) string {
 	return fmt.Sprintf(getUserGroups, userId)
 }
+
+func GetUserContentGroups(userId int) string {
+	return fmt.Sprintf(getUserContentGroups, userId)
+}
+
+func ApprovalGroupsQuery(pg string) string {
+	return fmt.Sprintf(getApprovalGroupsQuery, pagination(pg))
+}
+
+func ApprovalGroupMembersQuery(approvalGroupID string) string {
+	return fmt.Sprintf(getApprovalGroupMemberListQuery, approvalGroupID)
+}
+
+// UserApprovalGroupsQuery lists the approval groups a user is a member of,
+// with all of their members.
+func UserApprovalGroupsQuery(userId int, pg string) string {
+	return fmt.Sprintf(getUserApprovalGroupsQuery, userId, appendedPagination(pg))
+}
+
+func ApprovalLimitsQuery(pg string) string {
+	return fmt.Sprintf(getApprovalLimitsQuery, pagination(pg))
+}
+
+func ApprovalLimitQuery(approvalLimitID string) string {
+	return fmt.Sprintf(getApprovalLimitsQuery, fmt.Sprintf("id=%s", approvalLimitID))
+}
+
+// ApprovalLimitGrantQuery lists the users that have the approval limit
+// assigned as their requisition or expense approval limit.
+func ApprovalLimitGrantQuery(limitType ApprovalLimitType, approvalLimitID string, pg string) string {
+	return fmt.Sprintf(getApprovalLimitGrantListQuery, limitType.queryField(), approvalLimitID, appendedPagination(pg))
+}
+
+func GetUserApprovalLimits(userId int) string {
+	return fmt.Sprintf(getUserApprovalLimits, userId)
+}
+
+// DelegationsQuery lists the delegations created by a user that have not
+// ended by the given time, which includes future delegations.
+func DelegationsQuery(delegatorID string, endingAfter time.Time, pg string) string {
+	return fmt.Sprintf(
+		getDelegationsQuery,
+		endingAfter.UTC().Format(time.RFC3339),
+		fmt.Sprintf("delegator[id]=%s", delegatorID),
+		appendedPagination(pg),
+	)
+}
+
+// DelegatedToQuery lists the delegations to a user that have not ended by
+// the given time.
+func DelegatedToQuery(delegateID string, endingAfter time.Time, pg string) string {
+	return fmt.Sprintf(
+		getDelegationsQuery,
+		endingAfter.UTC().Format(time.RFC3339),
+		fmt.Sprintf("delegate[id]=%s", delegateID),
+		appendedPagination(pg),
+	)
+}
+
+// DelegateDelegationsQuery lists the delegations from a user to another one
+// that have not ended by the given time.
+func DelegateDelegationsQuery(delegatorID string, delegateID string, endingAfter time.Time, pg string) string {
+	return fmt.Sprintf(
+		getDelegationsQuery,
+		endingAfter.UTC().Format(time.RFC3339),
+		fmt.Sprintf("delegator[id]=%s&delegate[id]=%s", delegatorID, delegateID),
+		appendedPagination(pg),
+	)
+}
+
+func ApprovalChainsQuery(pg string) string {
+	return fmt.Sprintf(getApprovalChainsQuery, pagination(pg))
+}
+
+func ApprovalChainApproversQuery(approvalChainID string) string {
+	return fmt.Sprintf(getApprovalChainApproverListQuery, approvalChainID)
+}
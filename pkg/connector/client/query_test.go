@@ -0,0 +1,32 @@
+package client
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBudgetLinesQuery(t *testing.T) {
+	query := BudgetLinesQuery("FY 2026", "12")
+	require.Contains(t, query, `budgetLines(query: "period[name]=FY+2026&id[gt]=12")`)
+
+	query = BudgetLinesQuery(`FY26") { id } x: users(query: "&active=true`, "")
+	require.Equal(t, 1, strings.Count(query, `"`)/2)
+	require.NotContains(t, query, "&active=true")
+}
+
+func TestDelegateDelegationsQuery(t *testing.T) {
+	now := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	query := DelegateDelegationsQuery("7", "9", now, "")
+	require.Contains(t, query, `delegations(query: "end_date[gt]=2026-10-19T00:00:00Z&delegator[id]=7&delegate[id]=9")`)
+
+	query = DelegateDelegationsQuery("7", "9", now, "30")
+	require.Contains(t, query, `delegator[id]=7&delegate[id]=9&id[gt]=30")`)
+}
+
+func TestUserApprovalGroupsQuery(t *testing.T) {
+	require.Contains(t, UserApprovalGroupsQuery(7, ""), `approvalGroups(query: "users[id]=7")`)
+	require.Contains(t, UserApprovalGroupsQuery(7, "12"), `approvalGroups(query: "users[id]=7&id[gt]=12")`)
+}
@@ -1,13 +1,14 @@
 package client
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
 	"go.uber.org/zap"
@@ -70,7 +71,7 @@ func (c *Client) doGraphQLRequest(
 
 	if len(innerResponse.Errors) > 0 {
 		l.Error("Received errors from the server", zap.Any("errors", innerResponse.Errors))
-		return nil, nil, errors.New(innerResponse.Errors[0].Message)
+		return nil, nil, &GraphqlError{Message: innerResponse.Errors[0].Message}
 	}
 
 	if innerResponse.Data != nil {
@@ -95,6 +96,105 @@ func (c *Client) doRestRequest(
 	*http.Response,
 	*v2.RateLimitDescription,
 	error,
+) {
+	if !c.provisioning {
+		return nil, nil, fmt.Errorf("baton-coupa: provisioning is not enabled")
+	}
+
+	if c.dryRun {
+		return dryRunResponse(ctx, method, url, payload, target)
+	}
+
+	if c.journal == nil {
+		response, ratelimitData, _, err := c.sendRestRequest(ctx, method, url, payload, target)
+		return response, ratelimitData, err
+	}
+	return c.doJournaledRestRequest(ctx, method, url, payload, target)
+}
+
+// doJournaledRestRequest fetches the state of the resource before the write,
+// and appends the write to the journal once made. The write is not made when
+// its before state cannot be fetched, so that every journaled write can be
+// reverted.
+func (c *Client) doJournaledRestRequest(
+	ctx context.Context,
+	method string,
+	url *url.URL,
+	payload interface{},
+	target interface{},
+) (
+	*http.Response,
+	*v2.RateLimitDescription,
+	error,
+) {
+	task := journalTaskFrom(ctx)
+	entry := JournalEntry{
+		Task:   task,
+		UserId: journalUserId(url.Path, task),
+		Method: method,
+		Path:   url.Path,
+	}
+
+	before, err := c.getState(ctx, url)
+	if err != nil {
+		return nil, nil, fmt.Errorf("baton-coupa: fetching the state before the write to journal it: %w", err)
+	}
+	entry.Before = before
+
+	if payload != nil {
+		entry.Requested, err = json.Marshal(payload)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	response, ratelimitData, body, err := c.sendRestRequest(ctx, method, url, payload, target)
+	entry.Timestamp = time.Now().UTC()
+	if json.Valid(body) {
+		entry.Response = body
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	head, journalErr := c.journal.append(entry)
+	if journalErr != nil {
+		ctxzap.Extract(ctx).Error(
+			"baton-coupa: the write was made but could not be journaled",
+			zap.String("method", method),
+			zap.String("path", url.Path),
+			zap.Error(journalErr),
+		)
+		if err == nil {
+			err = journalErr
+		}
+		return response, ratelimitData, err
+	}
+
+	// The head is logged so that it is also recorded outside of the journal,
+	// to detect a journal rolled back along with its head.
+	ctxzap.Extract(ctx).Info(
+		"baton-coupa: journaled a write",
+		zap.String("method", method),
+		zap.String("path", url.Path),
+		zap.Int("journal_entries", head.Entries),
+		zap.String("journal_head", head.Hash),
+	)
+
+	return response, ratelimitData, err
+}
+
+func (c *Client) sendRestRequest(
+	ctx context.Context,
+	method string,
+	url *url.URL,
+	payload interface{},
+	target interface{},
+) (
+	*http.Response,
+	*v2.RateLimitDescription,
+	[]byte,
+	error,
 ) {
 	l := ctxzap.Extract(ctx)
 
@@ -108,7 +208,7 @@ func (c *Client) doRestRequest(
 
 	request, err := c.wrapper.NewRequest(ctx, method, url, options...)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	var ratelimitData v2.RateLimitDescription
 	response, err := c.wrapper.Do(
@@ -116,27 +216,71 @@ func (c *Client) doRestRequest(
 		uhttp.WithRatelimitData(&ratelimitData),
 	)
 	if err != nil {
-		return nil, &ratelimitData, err
+		return nil, &ratelimitData, nil, err
 	}
 	defer response.Body.Close()
 
 	bodyBytes, err := io.ReadAll(response.Body)
 	if err != nil {
-		return nil, &ratelimitData, err
+		return nil, &ratelimitData, nil, err
 	}
 
 	if response.StatusCode == http.StatusInternalServerError {
-		return response, &ratelimitData, fmt.Errorf("baton-coupa: internal server error %s", string(bodyBytes))
+		return response, &ratelimitData, bodyBytes, fmt.Errorf("baton-coupa: internal server error %s", string(bodyBytes))
 	}
 
 	if response.StatusCode == http.StatusBadRequest {
-		return response, &ratelimitData, fmt.Errorf("baton-coupa: bad request %s", string(bodyBytes))
+		return response, &ratelimitData, bodyBytes, fmt.Errorf("baton-coupa: bad request %s", string(bodyBytes))
+	}
+
+	if len(bodyBytes) == 0 {
+		return response, &ratelimitData, bodyBytes, nil
 	}
 
 	if err := json.Unmarshal(bodyBytes, &target); err != nil {
 		l.Error("Failed to unmarshal response body", zap.Error(err))
+		return nil, nil, bodyBytes, err
+	}
+
+	return response, &ratelimitData, bodyBytes, nil
+}
+
+// dryRunResponse logs a write instead of sending it, and simulates Coupa's
+// response by echoing the payload, as Coupa returns the updated fields.
+func dryRunResponse(
+	ctx context.Context,
+	method string,
+	url *url.URL,
+	payload interface{},
+	target interface{},
+) (
+	*http.Response,
+	*v2.RateLimitDescription,
+	error,
+) {
+	body := []byte("{}")
+	if payload != nil {
+		var err error
+		body, err = json.Marshal(payload)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	ctxzap.Extract(ctx).Info(
+		"baton-coupa: dry run, request not sent",
+		zap.String("method", method),
+		zap.String("path", url.Path),
+		zap.ByteString("body", body),
+	)
+
+	if err := json.Unmarshal(body, &target); err != nil {
 		return nil, nil, err
 	}
 
-	return response, &ratelimitData, nil
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader(body)),
+	}, &v2.RateLimitDescription{}, nil
 }
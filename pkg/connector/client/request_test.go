@@ -0,0 +1,153 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/conductorone/baton-sdk/pkg/uhttp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDryRun(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	baseUrl, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	c := &Client{
+		baseUrl:      baseUrl,
+		wrapper:      uhttp.NewBaseHttpClient(server.Client()),
+		initialized:  true,
+		provisioning: true,
+		dryRun:       true,
+	}
+
+	testCases := []struct {
+		message string
+		write   func(ctx context.Context) error
+	}{
+		{
+			message: "set roles",
+			write: func(ctx context.Context) error {
+				userResponse, _, err := c.SetRoles(ctx, 7, []int{1, 2})
+				if err != nil {
+					return err
+				}
+				require.Len(t, userResponse.Roles, 2)
+				return nil
+			},
+		}, {
+			message: "clear roles",
+			write: func(ctx context.Context) error {
+				userResponse, _, err := c.SetRoles(ctx, 7, make([]int, 0))
+				if err != nil {
+					return err
+				}
+				require.Empty(t, userResponse.Roles)
+				return nil
+			},
+		}, {
+			message: "remove licenses",
+			write: func(ctx context.Context) error {
+				userResponse, err := c.SetLicenses(ctx, 7, map[string]bool{"expense-user": false, "purchasing-user": false})
+				if err != nil {
+					return err
+				}
+				assigned, err := userResponse.Assigned()
+				if err != nil {
+					return err
+				}
+				require.Empty(t, assigned)
+				return nil
+			},
+		}, {
+			message: "add license",
+			write: func(ctx context.Context) error {
+				userResponse, err := c.SetLicense(ctx, 7, "expense-user", true)
+				if err != nil {
+					return err
+				}
+				require.True(t, userResponse.ExpenseUser)
+				return nil
+			},
+		}, {
+			message: "reassign requisition",
+			write: func(ctx context.Context) error {
+				owner, _, err := c.ReassignDocument(ctx, DocumentTypeRequisition, 5, 9)
+				if err != nil {
+					return err
+				}
+				require.Equal(t, &ResourceId{Id: 9}, owner)
+				return nil
+			},
+		}, {
+			message: "deactivate p-card",
+			write: func(ctx context.Context) error {
+				pcardResponse, _, err := c.SetPcardActive(ctx, 3, false)
+				if err != nil {
+					return err
+				}
+				require.False(t, pcardResponse.Active)
+				return nil
+			},
+		}, {
+			message: "delete delegation",
+			write: func(ctx context.Context) error {
+				_, err := c.DeleteDelegation(ctx, 3)
+				return err
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.message, func(t *testing.T) {
+			require.NoError(t, testCase.write(context.Background()))
+			require.Zero(t, requests.Load())
+		})
+	}
+}
+
+func TestJournaledWriteErrorState(t *testing.T) {
+	var writes atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writes.Add(1)
+		}
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, _ = w.Write([]byte(`{"errors":{"id":["not found"]}}`))
+	}))
+	defer server.Close()
+
+	baseUrl, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	j, err := openJournal(path, "secret")
+	require.NoError(t, err)
+
+	c := &Client{
+		baseUrl:      baseUrl,
+		wrapper:      uhttp.NewBaseHttpClient(server.Client()),
+		initialized:  true,
+		provisioning: true,
+		journal:      j,
+	}
+
+	_, _, err = c.SetRoles(context.Background(), 7, []int{1})
+	require.Error(t, err)
+	require.Zero(t, writes.Load())
+
+	_, err = ReadJournal(path)
+	require.True(t, errors.Is(err, os.ErrNotExist))
+}
@@ -0,0 +1,88 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/uhttp"
+)
+
+// SetUserActive activates or deactivates a user.
+// https://compass.coupa.com/en-us/products/product-documentation/integration-technical-documentation/the-coupa-core-api/resources/reference-data-resources/users-api-(users)
+func (c *Client) SetUserActive(
+	ctx context.Context,
+	userId int,
+	active bool,
+) (
+	*UserActiveResponse,
+	*v2.RateLimitDescription,
+	error,
+) {
+	err := c.Initialize(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	request := struct {
+		Active bool `json:"active"`
+	}{
+		Active: active,
+	}
+
+	var userResponse UserActiveResponse
+
+	response, rateLimit, err := c.doRestRequest(
+		ctx,
+		http.MethodPut,
+		c.baseUrl.JoinPath(fmt.Sprintf(setUserActivePath, userId)),
+		request,
+		&userResponse,
+	)
+	if err != nil {
+		return nil, rateLimit, err
+	}
+	defer response.Body.Close()
+
+	return &userResponse, rateLimit, nil
+}
+
+// ProbeUsers fetches the id of a single user from the REST API, to check that
+// the credentials can reach it. The write token is used when provisioning,
+// since it is the one REST requests are made with.
+func (c *Client) ProbeUsers(ctx context.Context) error {
+	err := c.Initialize(ctx)
+	if err != nil {
+		return err
+	}
+
+	token := c.readOnlyToken
+	if c.provisioning {
+		token = c.readWriteToken
+	}
+
+	usersUrl := c.baseUrl.JoinPath(apiPathUsers)
+	usersUrl.RawQuery = url.Values{
+		"fields": {`["id"]`},
+		"limit":  {"1"},
+	}.Encode()
+
+	request, err := c.wrapper.NewRequest(
+		ctx,
+		http.MethodGet,
+		usersUrl,
+		uhttp.WithAcceptJSONHeader(),
+		WithBearerToken(token),
+	)
+	if err != nil {
+		return err
+	}
+
+	response, err := c.wrapper.Do(request)
+	if response != nil {
+		response.Body.Close()
+	}
+	return err
+}
@@ -0,0 +1,52 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+)
+
+// SetUserWarehouses sets the inventory warehouses a user can operate in.
+// https://compass.coupa.com/en-us/products/product-documentation/integration-technical-documentation/the-coupa-core-api/resources/reference-data-resources/users-api-(users)
+func (c *Client) SetUserWarehouses(
+	ctx context.Context,
+	userId int,
+	warehouseIDs []int,
+) (
+	*UserWarehouses,
+	*v2.RateLimitDescription,
+	error,
+) {
+	err := c.Initialize(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	request := struct {
+		Warehouses []ResourceId `json:"warehouses"`
+	}{
+		Warehouses: make([]ResourceId, 0),
+	}
+
+	for _, warehouseId := range warehouseIDs {
+		request.Warehouses = append(request.Warehouses, ResourceId{Id: warehouseId})
+	}
+
+	var userResponse UserWarehouses
+
+	response, rateLimit, err := c.doRestRequest(
+		ctx,
+		http.MethodPut,
+		c.baseUrl.JoinPath(fmt.Sprintf(setWarehousesPath, userId)),
+		request,
+		&userResponse,
+	)
+	if err != nil {
+		return nil, rateLimit, err
+	}
+	defer response.Body.Close()
+
+	return &userResponse, rateLimit, nil
+}
@@ -3,6 +3,7 @@ package connector
 import (
 	"context"
 	"io"
+	"slices"
 
 	"github.com/conductorone/baton-coupa/pkg/connector/client"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
@@ -13,18 +14,49 @@ import (
 )
 
 type Connector struct {
-	client *client.Client
-	ctx    context.Context
+	client              *client.Client
+	ctx                 context.Context
+	budgetPeriod        string
+	syncOffboardingRisk bool
+	provisioning        bool
+	resourceTypes       []string
+	disabledSyncers     *disabledSyncers
+	guardrails          *guardrails
+	roleClassifier      *roleClassifier
 }
 
 // ResourceSyncers returns a ResourceSyncer for each resource type that should be synced from the upstream service.
 func (d *Connector) ResourceSyncers(ctx context.Context) []connectorbuilder.ResourceSyncer {
-	return []connectorbuilder.ResourceSyncer{
-		newUserBuilder(ctx, d.client),
-		newGroupBuilder(ctx, d.client),
-		newRoleBuilder(ctx, d.client),
-		newLicenseBuilder(ctx, d.client),
+	scopedByBusinessEntity := slices.Contains(d.resourceTypes, businessEntityResourceType.Id)
+	syncers := []connectorbuilder.ResourceSyncer{
+		newUserBuilder(ctx, d.client, d.syncOffboardingRisk, scopedByBusinessEntity, d.guardrails),
+		newGroupBuilder(ctx, d.client, scopedByBusinessEntity, d.guardrails),
+		newRoleBuilder(ctx, d.client, d.guardrails, d.roleClassifier),
+		newLicenseBuilder(ctx, d.client, d.guardrails),
+		newApprovalGroupBuilder(ctx, d.client, d.guardrails),
+		newApprovalChainBuilder(ctx, d.client),
+		newApprovalLimitBuilder(ctx, d.client, d.guardrails),
+		newBusinessEntityBuilder(ctx, d.client),
+		newSupplierBuilder(ctx, d.client),
+		newSupplierUserBuilder(ctx, d.client),
+		newOAuthClientBuilder(ctx, d.client),
+		newScopeBuilder(ctx, d.client),
+		newApiKeyBuilder(ctx, d.client),
+		newApiPermissionBuilder(ctx, d.client),
+		newPcardBuilder(ctx, d.client),
+		newWarehouseBuilder(ctx, d.client, d.guardrails),
+		newBudgetLineBuilder(ctx, d.client, d.budgetPeriod),
+		newDepartmentBuilder(ctx, d.client, d.guardrails),
 	}
+
+	rv := make([]connectorbuilder.ResourceSyncer, 0, len(syncers))
+	for _, syncer := range syncers {
+		if !slices.Contains(d.resourceTypes, syncer.ResourceType(ctx).Id) {
+			continue
+		}
+		rv = append(rv, newScopedSyncer(syncer, d.disabledSyncers))
+	}
+	return rv
 }
 
 // Asset takes an input AssetRef and attempts to fetch it using the connector's authenticated http client
@@ -37,15 +69,22 @@ func (d *Connector) Asset(ctx context.Context, asset *v2.AssetRef) (string, io.R
 func (d *Connector) Metadata(ctx context.Context) (*v2.ConnectorMetadata, error) {
 	return &v2.ConnectorMetadata{
 		DisplayName: "Coupa Connector",
-		Description: "Connector syncing Coupa users, groups, roles, and licenses",
+		Description: "Connector syncing Coupa users, groups, roles, licenses, and approvals",
 	}, nil
 }
 
 // Validate is called to ensure that the connector is properly configured. It should exercise any API credentials
-// to be sure that they are valid.
+// to be sure that they are valid. It runs a query for each resource type and a
+// REST read, and returns a report that tells auth, scope, network and instance
+// URL problems apart. Resource types missing a scope are disabled for the sync
+// instead of failing it.
 func (d *Connector) Validate(ctx context.Context) (annotations.Annotations, error) {
-	err := d.client.Initialize(ctx)
-	return nil, err
+	report, err := d.validate(ctx)
+	annos, annosErr := validationAnnotations(report)
+	if err != nil {
+		return annos, err
+	}
+	return annos, annosErr
 }
 
 // SetTokenSource this method makes Coupa implement the OAuth2Connector
@@ -56,21 +95,105 @@ func (d *Connector) SetTokenSource(tokenSource oauth2.TokenSource) {
 	d.client.ReadOnlyTokenSource = tokenSource
 }
 
+// Config is the configuration of the connector, read by the baton-coupa
+// command from its flags, environment and configuration file.
+type Config struct {
+	InstanceUrl         string
+	ClientId            string
+	ClientSecret        string
+	WriteClientId       string
+	WriteClientSecret   string
+	BudgetPeriod        string
+	SyncOffboardingRisk bool
+	Provisioning        bool
+	DryRun              bool
+	JournalPath         string
+	JournalKey          string
+	ResourceTypes       []string
+	Scopes              []string
+
+	AllowedRoles           []string
+	DeniedRoles            []string
+	AllowedGroups          []string
+	DeniedGroups           []string
+	AllowedLicenses        []string
+	DeniedLicenses         []string
+	AllowedApprovalGroups  []string
+	DeniedApprovalGroups   []string
+	AllowedApprovalLimits  []string
+	DeniedApprovalLimits   []string
+	AllowedWarehouses      []string
+	DeniedWarehouses       []string
+	AllowedDepartments     []string
+	DeniedDepartments      []string
+	ProtectedUsers         []string
+	CriticalRoles          []string
+	MinCriticalRoleHolders int
+	SodRulesPath           string
+
+	PrivilegedRoles   []string
+	UnprivilegedRoles []string
+}
+
 // New returns a new instance of the connector.
-func New(
-	ctx context.Context,
-	instanceUrl string,
-	clientId string,
-	clientSecret string,
-) (*Connector, error) {
+func New(ctx context.Context, config Config) (*Connector, error) {
+	resourceTypes, err := validateResourceTypes(config.ResourceTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	sod, err := loadSodRules(config.SodRulesPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// The scopes are those needed by the synced resource types, unless they
+	// are set in the configuration.
+	scopes := config.Scopes
+	if len(scopes) == 0 {
+		scopes = requiredScopes(resourceTypes, config.SyncOffboardingRisk)
+	}
+
 	coupaClient, err := client.New(
 		ctx,
-		instanceUrl,
-		clientId,
-		clientSecret,
+		config.InstanceUrl,
+		config.ClientId,
+		config.ClientSecret,
+		config.WriteClientId,
+		config.WriteClientSecret,
+		scopes,
+		config.Provisioning,
+		config.DryRun,
+		config.JournalPath,
+		config.JournalKey,
 	)
 	if err != nil {
 		return nil, err
 	}
-	return &Connector{client: coupaClient, ctx: ctx}, nil
+	return &Connector{
+		client:              coupaClient,
+		ctx:                 ctx,
+		budgetPeriod:        config.BudgetPeriod,
+		syncOffboardingRisk: config.SyncOffboardingRisk,
+		provisioning:        config.Provisioning,
+		resourceTypes:       resourceTypes,
+		disabledSyncers:     &disabledSyncers{},
+		guardrails: &guardrails{
+			roles:                  accessList{allowed: config.AllowedRoles, denied: config.DeniedRoles},
+			groups:                 accessList{allowed: config.AllowedGroups, denied: config.DeniedGroups},
+			licenses:               accessList{allowed: config.AllowedLicenses, denied: config.DeniedLicenses},
+			approvalGroups:         accessList{allowed: config.AllowedApprovalGroups, denied: config.DeniedApprovalGroups},
+			approvalLimits:         accessList{allowed: config.AllowedApprovalLimits, denied: config.DeniedApprovalLimits},
+			warehouses:             accessList{allowed: config.AllowedWarehouses, denied: config.DeniedWarehouses},
+			departments:            accessList{allowed: config.AllowedDepartments, denied: config.DeniedDepartments},
+			protectedUsers:         config.ProtectedUsers,
+			criticalRoles:          config.CriticalRoles,
+			minCriticalRoleHolders: config.MinCriticalRoleHolders,
+			sod:                    sod,
+		},
+		roleClassifier: &roleClassifier{
+			privilegedRoles:   config.PrivilegedRoles,
+			unprivilegedRoles: config.UnprivilegedRoles,
+		},
+	}, nil
 }
@@ -0,0 +1,31 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/conductorone/baton-coupa/pkg/connector/client"
+)
+
+// contentGroupAccessType is the type of the content group accesses reported
+// by the admin operations. Content groups are not synced as a resource type.
+const contentGroupAccessType = "content_group"
+
+func getUserContentGroups(ctx context.Context, coupaClient *client.Client, userId int) (*client.UserContentGroups, error) {
+	var target client.UserContentGroupsResponse
+	response, _, err := coupaClient.Query(
+		ctx,
+		client.GetUserContentGroups(userId),
+		&target,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if len(target.Users) == 0 {
+		return nil, fmt.Errorf("baton-coupa: user %d not found", userId)
+	}
+
+	return &target.Users[0], nil
+}
@@ -0,0 +1,295 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/conductorone/baton-coupa/pkg/connector/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	resourceSdk "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+const departmentMemberEntitlementName = "member"
+
+type departmentBuilder struct {
+	client     *client.Client
+	guardrails *guardrails
+}
+
+func (o *departmentBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return departmentResourceType
+}
+
+func departmentResource(department *client.Department, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	return resourceSdk.NewGroupResource(
+		department.Name,
+		departmentResourceType,
+		department.ID,
+		[]resourceSdk.GroupTraitOption{
+			resourceSdk.WithGroupProfile(
+				map[string]interface{}{
+					"id":     department.ID,
+					"name":   department.Name,
+					"active": department.Active,
+				},
+			),
+		},
+		resourceSdk.WithParentResourceID(parentResourceID),
+		resourceSdk.WithDescription(fmt.Sprintf("%s department in Coupa", department.Name)),
+	)
+}
+
+func (o *departmentBuilder) List(
+	ctx context.Context,
+	parentResourceID *v2.ResourceId,
+	pToken *pagination.Token,
+) (
+	[]*v2.Resource,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	logger := ctxzap.Extract(ctx)
+	logger.Debug("Starting Departments List", zap.String("token", pToken.Token))
+
+	outputResources := make([]*v2.Resource, 0)
+	var outputAnnotations annotations.Annotations
+
+	var target client.DepartmentsQueryResponse
+	response, ratelimitData, err := o.client.Query(
+		ctx,
+		client.DepartmentsQuery(pToken.Token),
+		&target,
+	)
+	outputAnnotations.WithRateLimiting(ratelimitData)
+	if err != nil {
+		return nil, "", outputAnnotations, err
+	}
+	defer response.Body.Close()
+
+	lastId := ""
+	for _, department := range target.Departments {
+		resource, err := departmentResource(department, parentResourceID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		outputResources = append(outputResources, resource)
+		lastId = strconv.Itoa(department.ID)
+	}
+
+	return outputResources, lastId, outputAnnotations, nil
+}
+
+func (o *departmentBuilder) Entitlements(
+	_ context.Context,
+	resource *v2.Resource,
+	_ *pagination.Token,
+) (
+	[]*v2.Entitlement,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	return []*v2.Entitlement{
+		entitlement.NewAssignmentEntitlement(
+			resource,
+			departmentMemberEntitlementName,
+			entitlement.WithGrantableTo(userResourceType),
+			entitlement.WithDisplayName(
+				fmt.Sprintf("%s Department", resource.DisplayName),
+			),
+			entitlement.WithDescription(
+				fmt.Sprintf("%s department in Coupa", resource.DisplayName),
+			),
+		),
+	}, "", nil, nil
+}
+
+func (o *departmentBuilder) Grants(
+	ctx context.Context,
+	resource *v2.Resource,
+	pToken *pagination.Token,
+) (
+	[]*v2.Grant,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	logger := ctxzap.Extract(ctx)
+
+	departmentId := resource.Id.Resource
+
+	logger.Debug(
+		"Starting Departments Grants",
+		zap.String("department_id", departmentId),
+		zap.String("token", pToken.Token),
+	)
+
+	outputGrants := make([]*v2.Grant, 0)
+	var outputAnnotations annotations.Annotations
+
+	var target client.DepartmentGrantsQueryResponse
+	response, ratelimitData, err := o.client.Query(
+		ctx,
+		client.DepartmentGrantQuery(departmentId, pToken.Token),
+		&target,
+	)
+	outputAnnotations.WithRateLimiting(ratelimitData)
+	if err != nil {
+		return nil, "", outputAnnotations, err
+	}
+	defer response.Body.Close()
+
+	lastId := ""
+	for _, user := range target.Users {
+		userId := strconv.Itoa(user.Id)
+		outputGrants = append(
+			outputGrants,
+			grant.NewGrant(
+				resource,
+				departmentMemberEntitlementName,
+				&v2.ResourceId{
+					ResourceType: userResourceType.Id,
+					Resource:     userId,
+				},
+			),
+		)
+		lastId = userId
+	}
+
+	return outputGrants, lastId, outputAnnotations, nil
+}
+
+// Grant moves the user to the department. A user belongs to a single
+// department, so this replaces their current one.
+func (o *departmentBuilder) Grant(ctx context.Context, resource *v2.Resource, entitlement *v2.Entitlement) ([]*v2.Grant, annotations.Annotations, error) {
+	err := o.guardrails.checkGrant(entitlement.Resource.Id.ResourceType, entitlement.Resource.Id.Resource, resource.Id.Resource)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if resource.Id.ResourceType != userResourceType.Id {
+		return nil, nil, fmt.Errorf("baton-coupa: principal resource type is not %s", userResourceType.Id)
+	}
+
+	departmentId, err := strconv.Atoi(entitlement.Resource.Id.Resource)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	userId, err := strconv.Atoi(resource.Id.Resource)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user, err := o.getUserDepartment(ctx, userId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if user.Department != nil && user.Department.Id == departmentId {
+		return []*v2.Grant{}, annotations.New(&v2.GrantAlreadyExists{}), nil
+	}
+
+	userResponse, _, err := o.client.SetUserDepartment(ctx, userId, &departmentId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if userResponse.Department == nil || userResponse.Department.Id != departmentId {
+		return nil, nil, errors.New("baton-coupa: department not set")
+	}
+
+	newGrant := grant.NewGrant(
+		entitlement.Resource,
+		departmentMemberEntitlementName,
+		resource.Id,
+	)
+
+	return []*v2.Grant{newGrant}, nil, nil
+}
+
+func (o *departmentBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	err := o.guardrails.checkUser(grant.Principal.Id.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	l := ctxzap.Extract(ctx)
+
+	if grant.Principal.Id.ResourceType != userResourceType.Id {
+		return nil, fmt.Errorf("baton-coupa: principal resource type is not %s", userResourceType.Id)
+	}
+
+	departmentId, err := strconv.Atoi(grant.Entitlement.Resource.Id.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	userId, err := strconv.Atoi(grant.Principal.Id.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := o.getUserDepartment(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Department == nil || user.Department.Id != departmentId {
+		l.Info(
+			"baton-coupa: department not found in user",
+		)
+
+		return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+	}
+
+	userResponse, _, err := o.client.SetUserDepartment(ctx, userId, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if userResponse.Department != nil {
+		return nil, errors.New("baton-coupa: department was not removed")
+	}
+
+	return nil, nil
+}
+
+func (o *departmentBuilder) getUserDepartment(ctx context.Context, userId int) (*client.UserDepartment, error) {
+	var target client.UserDepartmentResponse
+	response, _, err := o.client.Query(
+		ctx,
+		client.GetUserDepartment(userId),
+		&target,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if len(target.Users) == 0 {
+		return nil, errors.New("baton-coupa: user not found")
+	}
+
+	if len(target.Users) > 1 {
+		return nil, fmt.Errorf("baton-coupa: multiple users found for id %d", userId)
+	}
+
+	return &target.Users[0], nil
+}
+
+func newDepartmentBuilder(ctx context.Context, client *client.Client, guardrails *guardrails) *departmentBuilder {
+	return &departmentBuilder{
+		client:     client,
+		guardrails: guardrails,
+	}
+}
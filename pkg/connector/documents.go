@@ -0,0 +1,155 @@
+package connector
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/conductorone/baton-coupa/pkg/connector/client"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+// documentCounter counts the in-flight documents assigned to each user. The
+// counts are loaded by paging through every open document, the first time
+// they are needed after a reset.
+type documentCounter struct {
+	client *client.Client
+	mu     sync.Mutex
+	counts map[int]map[client.DocumentType]int
+}
+
+func newDocumentCounter(client *client.Client) *documentCounter {
+	return &documentCounter{
+		client: client,
+	}
+}
+
+// reset drops the loaded counts, so the next sync loads them again.
+func (d *documentCounter) reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.counts = nil
+}
+
+// Counts returns the number of documents of each type assigned to the user.
+func (d *documentCounter) Counts(ctx context.Context, userId int) (map[client.DocumentType]int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.counts == nil {
+		counts, err := d.load(ctx)
+		if err != nil {
+			return nil, err
+		}
+		d.counts = counts
+	}
+
+	userCounts := make(map[client.DocumentType]int)
+	for _, documentType := range client.DocumentTypes {
+		userCounts[documentType] = d.counts[userId][documentType]
+	}
+
+	return userCounts, nil
+}
+
+func (d *documentCounter) load(ctx context.Context) (map[int]map[client.DocumentType]int, error) {
+	logger := ctxzap.Extract(ctx)
+
+	counts := make(map[int]map[client.DocumentType]int)
+	for _, documentType := range client.DocumentTypes {
+		logger.Debug("Counting documents", zap.String("document_type", string(documentType)))
+
+		lastId := ""
+		for {
+			var target client.DocumentsQueryResponse
+			response, _, err := d.client.Query(
+				ctx,
+				client.DocumentsQuery(documentType, lastId),
+				&target,
+			)
+			if err != nil {
+				return nil, err
+			}
+			response.Body.Close()
+
+			if len(target.Documents) == 0 {
+				break
+			}
+
+			for _, document := range target.Documents {
+				lastId = strconv.Itoa(document.ID)
+				if document.Owner == nil {
+					continue
+				}
+				if counts[document.Owner.Id] == nil {
+					counts[document.Owner.Id] = make(map[client.DocumentType]int)
+				}
+				counts[document.Owner.Id][documentType]++
+			}
+		}
+	}
+
+	return counts, nil
+}
+
+// UserDocumentsReport lists the ids of the pending approvals and open
+// documents assigned to a user, by document type.
+type UserDocumentsReport struct {
+	UserId    int                           `json:"user_id"`
+	Documents map[client.DocumentType][]int `json:"documents"`
+}
+
+// ListUserDocuments lists the pending approvals and open documents assigned to
+// a user, which get stuck when the user is deactivated. It only reads from
+// Coupa.
+func (d *Connector) ListUserDocuments(ctx context.Context, userId int) (*UserDocumentsReport, error) {
+	documents, err := getUserDocuments(ctx, d.client, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &UserDocumentsReport{
+		UserId:    userId,
+		Documents: make(map[client.DocumentType][]int),
+	}
+	for _, documentType := range client.DocumentTypes {
+		documentIds := make([]int, 0, len(documents[documentType]))
+		for _, document := range documents[documentType] {
+			documentIds = append(documentIds, document.ID)
+		}
+		report.Documents[documentType] = documentIds
+	}
+
+	return report, nil
+}
+
+// getUserDocuments pages through the in-flight documents of each type
+// assigned to the user.
+func getUserDocuments(ctx context.Context, coupaClient *client.Client, userId int) (map[client.DocumentType][]*client.Document, error) {
+	documents := make(map[client.DocumentType][]*client.Document)
+	for _, documentType := range client.DocumentTypes {
+		lastId := ""
+		for {
+			var target client.DocumentsQueryResponse
+			response, _, err := coupaClient.Query(
+				ctx,
+				client.UserDocumentsQuery(documentType, userId, lastId),
+				&target,
+			)
+			if err != nil {
+				return nil, err
+			}
+			response.Body.Close()
+
+			if len(target.Documents) == 0 {
+				break
+			}
+
+			documents[documentType] = append(documents[documentType], target.Documents...)
+			lastId = strconv.Itoa(target.Documents[len(target.Documents)-1].ID)
+		}
+	}
+	return documents, nil
+}
@@ -0,0 +1,24 @@
+package connector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/conductorone/baton-coupa/pkg/connector/client"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDocumentCounterReset(t *testing.T) {
+	counter := newDocumentCounter(nil)
+	counter.counts = map[int]map[client.DocumentType]int{
+		7: {client.DocumentTypePendingApproval: 2},
+	}
+
+	counts, err := counter.Counts(context.Background(), 7)
+	require.NoError(t, err)
+	require.Equal(t, 2, counts[client.DocumentTypePendingApproval])
+	require.Equal(t, 0, counts[client.DocumentTypeInvoice])
+
+	counter.reset()
+	require.Nil(t, counter.counts)
+}
@@ -22,7 +22,11 @@ import (
 const groupMemberEntitlementName = "member"
 
 type groupBuilder struct {
-	client *client.Client
+	client     *client.Client
+	guardrails *guardrails
+	// scopedByBusinessEntity is set when business entities are synced, so
+	// groups are listed under their business entity.
+	scopedByBusinessEntity bool
 }
 
 func (o *groupBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
@@ -61,10 +65,15 @@ func (o *groupBuilder) List(
 	outputResources := make([]*v2.Resource, 0)
 	var outputAnnotations annotations.Annotations
 
+	query := client.GroupsQuery(pToken.Token)
+	if parentResourceID != nil && parentResourceID.ResourceType == businessEntityResourceType.Id {
+		query = client.BusinessEntityGroupsQuery(parentResourceID.Resource, pToken.Token)
+	}
+
 	var target client.GroupsQueryResponse
 	response, ratelimitData, err := o.client.Query(
 		ctx,
-		client.GroupsQuery(pToken.Token),
+		query,
 		&target,
 	)
 	outputAnnotations.WithRateLimiting(ratelimitData)
@@ -75,12 +84,16 @@ func (o *groupBuilder) List(
 
 	lastId := ""
 	for _, group := range target.UserGroups {
+		lastId = strconv.Itoa(group.ID)
+		if o.scopedByBusinessEntity && !listedUnder(group.BusinessEntities, parentResourceID) {
+			continue
+		}
+
 		resource, err := groupResource(group, parentResourceID)
 		if err != nil {
 			return nil, "", nil, err
 		}
 		outputResources = append(outputResources, resource)
-		lastId = strconv.Itoa(group.ID)
 	}
 
 	return outputResources, lastId, outputAnnotations, nil
@@ -167,6 +180,11 @@ func (o *groupBuilder) Grants(
 }
 
 func (o *groupBuilder) Grant(ctx context.Context, resource *v2.Resource, entitlement *v2.Entitlement) ([]*v2.Grant, annotations.Annotations, error) {
+	err := o.guardrails.checkGrant(entitlement.Resource.Id.ResourceType, entitlement.Resource.Id.Resource, resource.Id.Resource)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	l := ctxzap.Extract(ctx)
 
 	groupIdToAdd, err := strconv.Atoi(entitlement.Resource.Id.Resource)
@@ -179,6 +197,11 @@ func (o *groupBuilder) Grant(ctx context.Context, resource *v2.Resource, entitle
 		return nil, nil, err
 	}
 
+	err = o.guardrails.checkSod(ctx, o.client, userId, sodAccessOf(entitlement))
+	if err != nil {
+		return nil, nil, err
+	}
+
 	user, err := o.getUserGroupsResponse(ctx, userId)
 	if err != nil {
 		return nil, nil, err
@@ -224,6 +247,11 @@ func (o *groupBuilder) Grant(ctx context.Context, resource *v2.Resource, entitle
 }
 
 func (o *groupBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	err := o.guardrails.checkUser(grant.Principal.Id.Resource)
+	if err != nil {
+		return nil, err
+	}
+
 	l := ctxzap.Extract(ctx)
 
 	if grant.Principal.Id.ResourceType != userResourceType.Id {
@@ -312,8 +340,10 @@ func (o *groupBuilder) getUserGroupsResponse(ctx context.Context, userId int) (*
 	return &target.Users[0], nil
 }
 
-func newGroupBuilder(ctx context.Context, client *client.Client) *groupBuilder {
+func newGroupBuilder(ctx context.Context, client *client.Client, scopedByBusinessEntity bool, guardrails *guardrails) *groupBuilder {
 	return &groupBuilder{
-		client: client,
+		client:                 client,
+		guardrails:             guardrails,
+		scopedByBusinessEntity: scopedByBusinessEntity,
 	}
 }
@@ -0,0 +1,141 @@
+package connector
+
+import (
+	"context"
+	"slices"
+	"strconv"
+
+	"github.com/conductorone/baton-coupa/pkg/connector/client"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// accessList restricts which ids can be granted. When allowed is set, only
+// its ids can be granted; ids in denied can never be granted.
+type accessList struct {
+	allowed []string
+	denied  []string
+}
+
+func (l accessList) check(resourceType string, id string) error {
+	if slices.Contains(l.denied, id) {
+		return status.Errorf(codes.PermissionDenied, "baton-coupa: %s %s is in the deny list and cannot be granted", resourceType, id)
+	}
+	if len(l.allowed) > 0 && !slices.Contains(l.allowed, id) {
+		return status.Errorf(codes.PermissionDenied, "baton-coupa: %s %s is not in the allow list and cannot be granted", resourceType, id)
+	}
+	return nil
+}
+
+// guardrails are the configured limits on provisioning. Each provisionable
+// resource type has its allow and deny lists. Protected users, such as
+// integration service accounts, never have their access changed. Critical
+// roles, such as Admin, must keep a minimum of active holders.
+type guardrails struct {
+	roles                  accessList
+	groups                 accessList
+	licenses               accessList
+	approvalGroups         accessList
+	approvalLimits         accessList
+	warehouses             accessList
+	departments            accessList
+	protectedUsers         []string
+	criticalRoles          []string
+	minCriticalRoleHolders int
+	sod                    *sodRules
+}
+
+// checkUser denies any change to the access of a protected user.
+func (g *guardrails) checkUser(userId string) error {
+	if slices.Contains(g.protectedUsers, userId) {
+		return status.Errorf(codes.PermissionDenied, "baton-coupa: user %s is protected and its access cannot be changed", userId)
+	}
+	return nil
+}
+
+// checkGrant denies granting an entitlement of the given resource to a
+// protected user, or when the resource is not grantable.
+func (g *guardrails) checkGrant(resourceType string, resourceId string, userId string) error {
+	err := g.checkUser(userId)
+	if err != nil {
+		return err
+	}
+
+	switch resourceType {
+	case roleResourceType.Id:
+		return g.roles.check(resourceType, resourceId)
+	case groupResourceType.Id:
+		return g.groups.check(resourceType, resourceId)
+	case licenseResourceType.Id:
+		return g.licenses.check(resourceType, resourceId)
+	case approvalGroupResourceType.Id:
+		return g.approvalGroups.check(resourceType, resourceId)
+	case approvalLimitResourceType.Id:
+		return g.approvalLimits.check(resourceType, resourceId)
+	case warehouseResourceType.Id:
+		return g.warehouses.check(resourceType, resourceId)
+	case departmentResourceType.Id:
+		return g.departments.check(resourceType, resourceId)
+	}
+	return nil
+}
+
+// checkCriticalRoles denies removing the given roles from a user, by revoking
+// them or deactivating the user, when a critical role among them would be
+// left with fewer active holders than required, and never with none.
+func (g *guardrails) checkCriticalRoles(ctx context.Context, coupaClient *client.Client, userId int, roles []client.Role) error {
+	minHolders := max(g.minCriticalRoleHolders, 1)
+
+	for _, role := range roles {
+		if !slices.Contains(g.criticalRoles, role.Name) {
+			continue
+		}
+
+		holders, err := countOtherActiveHolders(ctx, coupaClient, role.ID, userId, minHolders)
+		if err != nil {
+			return err
+		}
+		if holders < minHolders {
+			return status.Errorf(
+				codes.PermissionDenied,
+				"baton-coupa: removing critical role %s from user %d would leave %d active holders, at least %d are required",
+				role.Name,
+				userId,
+				holders,
+				minHolders,
+			)
+		}
+	}
+	return nil
+}
+
+// countOtherActiveHolders counts the active holders of a role other than the
+// given user, up to limit.
+func countOtherActiveHolders(ctx context.Context, coupaClient *client.Client, roleId int, userId int, limit int) (int, error) {
+	count := 0
+	lastId := ""
+	for count < limit {
+		var target client.RoleGrantsQueryResponse
+		response, _, err := coupaClient.Query(
+			ctx,
+			client.ActiveRoleHoldersQuery(strconv.Itoa(roleId), lastId),
+			&target,
+		)
+		if err != nil {
+			return 0, err
+		}
+		response.Body.Close()
+
+		if len(target.Users) == 0 {
+			break
+		}
+
+		for _, user := range target.Users {
+			if user.Id != userId {
+				count++
+			}
+		}
+		lastId = strconv.Itoa(target.Users[len(target.Users)-1].Id)
+	}
+	return count, nil
+}
@@ -0,0 +1,88 @@
+package connector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestGuardrailsCheckGrant(t *testing.T) {
+	g := &guardrails{
+		roles:          accessList{denied: []string{"1"}},
+		groups:         accessList{allowed: []string{"10", "11"}},
+		approvalLimits: accessList{denied: []string{"7"}},
+		departments:    accessList{allowed: []string{"3"}},
+		protectedUsers: []string{"100"},
+	}
+
+	testCases := []struct {
+		message      string
+		resourceType string
+		resourceId   string
+		userId       string
+		allowed      bool
+	}{
+		{
+			message:      "role not denied",
+			resourceType: roleResourceType.Id,
+			resourceId:   "2",
+			userId:       "200",
+			allowed:      true,
+		}, {
+			message:      "denied role",
+			resourceType: roleResourceType.Id,
+			resourceId:   "1",
+			userId:       "200",
+		}, {
+			message:      "allowed group",
+			resourceType: groupResourceType.Id,
+			resourceId:   "11",
+			userId:       "200",
+			allowed:      true,
+		}, {
+			message:      "group not allowed",
+			resourceType: groupResourceType.Id,
+			resourceId:   "12",
+			userId:       "200",
+		}, {
+			message:      "license without lists",
+			resourceType: licenseResourceType.Id,
+			resourceId:   "expense-user",
+			userId:       "200",
+			allowed:      true,
+		}, {
+			message:      "denied approval limit",
+			resourceType: approvalLimitResourceType.Id,
+			resourceId:   "7",
+			userId:       "200",
+		}, {
+			message:      "department not allowed",
+			resourceType: departmentResourceType.Id,
+			resourceId:   "4",
+			userId:       "200",
+		}, {
+			message:      "warehouse without lists",
+			resourceType: warehouseResourceType.Id,
+			resourceId:   "5",
+			userId:       "200",
+			allowed:      true,
+		}, {
+			message:      "protected user",
+			resourceType: warehouseResourceType.Id,
+			resourceId:   "5",
+			userId:       "100",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.message, func(t *testing.T) {
+			err := g.checkGrant(testCase.resourceType, testCase.resourceId, testCase.userId)
+			if testCase.allowed {
+				require.NoError(t, err)
+				return
+			}
+			require.Equal(t, codes.PermissionDenied, status.Code(err))
+		})
+	}
+}
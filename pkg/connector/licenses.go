@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/conductorone/baton-coupa/pkg/connector/client"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
@@ -18,8 +19,80 @@ import (
 
 const licenseEntitlementName = "assigned"
 
+// coupaLicenses are the Coupa licenses, each one is a flag on the user.
+var coupaLicenses = []*client.License{
+	{
+		Name:        "AI classification",
+		ID:          "aic-user",
+		Description: "An AI Spend Classification license",
+	},
+	{
+		Name:        "Analytics",
+		ID:          "analytics-user",
+		Description: "An Analytics license",
+	},
+	{
+		Name:        "Contingent Workforce",
+		ID:          "ccw-user",
+		Description: "A Contingent Workforce license",
+	},
+	{
+		// This does not revoke
+		Name:        "Contracts",
+		ID:          "contracts-user",
+		Description: "A Contracts license",
+	},
+	{
+		Name:        "Expense",
+		ID:          "expense-user",
+		Description: "An Expense license",
+	},
+	{
+		Name:        "Inventory",
+		ID:          "inventory-user",
+		Description: "An Inventory license",
+	},
+	{
+		// This does not revoke
+		Name:        "Purchasing",
+		ID:          "purchasing-user",
+		Description: "A Purchasing license",
+	},
+	{
+		Name:        "Risk Assess",
+		ID:          "risk-assess-user",
+		Description: "A Risk Assess license",
+	},
+	{
+		Name:        "Sourcing",
+		ID:          "sourcing-user",
+		Description: "A Sourcing license",
+	},
+	{
+		Name:        "Spend Guard",
+		ID:          "spend-guard-user",
+		Description: "A Spend Guard license",
+	},
+	{
+		Name:        "Supply Chain",
+		ID:          "supply-chain-user",
+		Description: "A Supply Chain license",
+	},
+	{
+		Name:        "Travel",
+		ID:          "travel-user",
+		Description: "A Travel license",
+	},
+	{
+		Name:        "Treasury",
+		ID:          "treasury_user",
+		Description: "A Treasury license",
+	},
+}
+
 type licenseBuilder struct {
-	client *client.Client
+	client     *client.Client
+	guardrails *guardrails
 }
 
 func (o *licenseBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
@@ -47,75 +120,6 @@ func (o *licenseBuilder) List(
 	annotations.Annotations,
 	error,
 ) {
-	coupaLicenses := []*client.License{
-		{
-			Name:        "AI classification",
-			ID:          "aic-user",
-			Description: "An AI Spend Classification license",
-		},
-		{
-			Name:        "Analytics",
-			ID:          "analytics-user",
-			Description: "An Analytics license",
-		},
-		{
-			Name:        "Contingent Workforce",
-			ID:          "ccw-user",
-			Description: "A Contingent Workforce license",
-		},
-		{
-			// This does not revoke
-			Name:        "Contracts",
-			ID:          "contracts-user",
-			Description: "A Contracts license",
-		},
-		{
-			Name:        "Expense",
-			ID:          "expense-user",
-			Description: "An Expense license",
-		},
-		{
-			Name:        "Inventory",
-			ID:          "inventory-user",
-			Description: "An Inventory license",
-		},
-		{
-			// This does not revoke
-			Name:        "Purchasing",
-			ID:          "purchasing-user",
-			Description: "A Purchasing license",
-		},
-		{
-			Name:        "Risk Assess",
-			ID:          "risk-assess-user",
-			Description: "A Risk Assess license",
-		},
-		{
-			Name:        "Sourcing",
-			ID:          "sourcing-user",
-			Description: "A Sourcing license",
-		},
-		{
-			Name:        "Spend Guard",
-			ID:          "spend-guard-user",
-			Description: "A Spend Guard license",
-		},
-		{
-			Name:        "Supply Chain",
-			ID:          "supply-chain-user",
-			Description: "A Supply Chain license",
-		},
-		{
-			Name:        "Travel",
-			ID:          "travel-user",
-			Description: "A Travel license",
-		},
-		{
-			Name:        "Treasury",
-			ID:          "treasury_user",
-			Description: "A Treasury license",
-		},
-	}
 	outputResources := make([]*v2.Resource, 0)
 	for _, license := range coupaLicenses {
 		resource, err := licenseResource(license, parentResourceID)
@@ -209,6 +213,11 @@ func (o *licenseBuilder) Grants(
 }
 
 func (o *licenseBuilder) Grant(ctx context.Context, resource *v2.Resource, entitlement *v2.Entitlement) ([]*v2.Grant, annotations.Annotations, error) {
+	err := o.guardrails.checkGrant(entitlement.Resource.Id.ResourceType, entitlement.Resource.Id.Resource, resource.Id.Resource)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	licenseIdToAdd := entitlement.Resource.Id.Resource
 
 	userId, err := strconv.Atoi(resource.Id.Resource)
@@ -216,6 +225,11 @@ func (o *licenseBuilder) Grant(ctx context.Context, resource *v2.Resource, entit
 		return nil, nil, err
 	}
 
+	err = o.guardrails.checkSod(ctx, o.client, userId, sodAccessOf(entitlement))
+	if err != nil {
+		return nil, nil, err
+	}
+
 	_, err = o.client.SetLicense(ctx, userId, licenseIdToAdd, true)
 	if err != nil {
 		return nil, nil, err
@@ -234,6 +248,11 @@ func (o *licenseBuilder) Grant(ctx context.Context, resource *v2.Resource, entit
 }
 
 func (o *licenseBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	err := o.guardrails.checkUser(grant.Principal.Id.Resource)
+	if err != nil {
+		return nil, err
+	}
+
 	if grant.Principal.Id.ResourceType != userResourceType.Id {
 		return nil, fmt.Errorf("baton-coupa: principal resource type is not %s", userResourceType.Id)
 	}
@@ -253,8 +272,53 @@ func (o *licenseBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotatio
 	return nil, nil
 }
 
-func newLicenseBuilder(ctx context.Context, client *client.Client) *licenseBuilder {
+// licenseQueryField returns the GraphQL name of a license flag, for example
+// spendGuardUser for spend-guard-user.
+func licenseQueryField(licenseId string) string {
+	parts := strings.FieldsFunc(licenseId, func(r rune) bool {
+		return r == '-' || r == '_'
+	})
+	for i := 1; i < len(parts); i++ {
+		parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+	}
+	return strings.Join(parts, "")
+}
+
+// getUserLicenses returns the ids of the licenses assigned to a user.
+func getUserLicenses(ctx context.Context, coupaClient *client.Client, userId int) ([]string, error) {
+	fields := make([]string, 0, len(coupaLicenses))
+	for _, license := range coupaLicenses {
+		fields = append(fields, licenseQueryField(license.ID))
+	}
+
+	var target client.UserLicensesResponse
+	response, _, err := coupaClient.Query(
+		ctx,
+		client.GetUserLicenses(userId, fields),
+		&target,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if len(target.Users) == 0 {
+		return nil, fmt.Errorf("baton-coupa: user %d not found", userId)
+	}
+
+	licenseIds := make([]string, 0)
+	for _, license := range coupaLicenses {
+		if assigned, ok := target.Users[0][licenseQueryField(license.ID)].(bool); ok && assigned {
+			licenseIds = append(licenseIds, license.ID)
+		}
+	}
+
+	return licenseIds, nil
+}
+
+func newLicenseBuilder(ctx context.Context, client *client.Client, guardrails *guardrails) *licenseBuilder {
 	return &licenseBuilder{
-		client: client,
+		client:     client,
+		guardrails: guardrails,
 	}
 }
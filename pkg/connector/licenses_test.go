@@ -0,0 +1,34 @@
+package connector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLicenseQueryField(t *testing.T) {
+	testCases := []struct {
+		message  string
+		license  string
+		expected string
+	}{
+		{
+			message:  "single word",
+			license:  "aic-user",
+			expected: "aicUser",
+		}, {
+			message:  "several words",
+			license:  "spend-guard-user",
+			expected: "spendGuardUser",
+		}, {
+			message:  "underscore",
+			license:  "treasury_user",
+			expected: "treasuryUser",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.message, func(t *testing.T) {
+			require.Equal(t, testCase.expected, licenseQueryField(testCase.license))
+		})
+	}
+}
@@ -0,0 +1,265 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/conductorone/baton-coupa/pkg/connector/client"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/status"
+)
+
+// AccessChange is an access a target user gets from a reference user.
+type AccessChange struct {
+	ResourceType string `json:"resource_type"`
+	Id           string `json:"id"`
+	Name         string `json:"name"`
+}
+
+// MirrorAccessReport is the diff between the access of a reference user and
+// a target user. When DryRun is set, nothing was changed in Coupa.
+type MirrorAccessReport struct {
+	SourceUserId int            `json:"source_user_id"`
+	TargetUserId int            `json:"target_user_id"`
+	DryRun       bool           `json:"dry_run"`
+	Changes      []AccessChange `json:"changes"`
+	Skipped      []string       `json:"skipped,omitempty"`
+}
+
+// grantable checks an access against the guardrails, including the
+// separation-of-duties rules given the accesses the target already holds or
+// gets from the mirroring, and records it as skipped when it cannot be
+// granted.
+func (r *MirrorAccessReport) grantable(ctx context.Context, g *guardrails, list accessList, accesses *[]sodAccess, access sodAccess) bool {
+	err := list.check(access.ResourceType, access.Id)
+	if err == nil {
+		err = g.checkSodAccess(ctx, r.TargetUserId, *accesses, access)
+	}
+	if err != nil {
+		r.Skipped = append(r.Skipped, status.Convert(err).Message())
+		return false
+	}
+	*accesses = append(*accesses, access)
+	return true
+}
+
+// MirrorAccess gives the target user the roles, user groups, content groups
+// and licenses of the source user. Access the target already has is kept, and access denied by
+// the guardrails or the separation-of-duties rules is skipped. With dryRun,
+// only the diff is computed.
+func (d *Connector) MirrorAccess(ctx context.Context, sourceUserId int, targetUserId int, dryRun bool) (*MirrorAccessReport, error) {
+	l := ctxzap.Extract(ctx)
+	l.Info(
+		"baton-coupa: mirroring user access",
+		zap.Int("source_user_id", sourceUserId),
+		zap.Int("target_user_id", targetUserId),
+		zap.Bool("dry_run", dryRun),
+	)
+
+	if sourceUserId == targetUserId {
+		return nil, errors.New("baton-coupa: source and target users must be different")
+	}
+
+	err := d.guardrails.checkUser(strconv.Itoa(targetUserId))
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = client.WithJournalTask(ctx, client.JournalTask{
+		Operation: "mirror_access",
+		Principal: strconv.Itoa(targetUserId),
+	})
+
+	report := &MirrorAccessReport{
+		SourceUserId: sourceUserId,
+		TargetUserId: targetUserId,
+		DryRun:       dryRun,
+		Changes:      make([]AccessChange, 0),
+	}
+
+	sodAccesses, err := d.guardrails.sod.getUserSodAccesses(ctx, d.client, targetUserId)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := newRoleBuilder(ctx, d.client, d.guardrails, nil)
+	sourceRoles, err := roles.getUserRoles(ctx, sourceUserId)
+	if err != nil {
+		return nil, err
+	}
+	targetRoles, err := roles.getUserRoles(ctx, targetUserId)
+	if err != nil {
+		return nil, err
+	}
+	newRoleIds := make([]int, 0)
+	for _, role := range targetRoles.Roles {
+		newRoleIds = append(newRoleIds, role.ID)
+	}
+	for _, role := range sourceRoles.Roles {
+		if slices.Contains(newRoleIds, role.ID) {
+			continue
+		}
+		access := sodAccess{ResourceType: roleResourceType.Id, Id: strconv.Itoa(role.ID), Name: role.Name}
+		if !report.grantable(ctx, d.guardrails, d.guardrails.roles, &sodAccesses, access) {
+			continue
+		}
+		newRoleIds = append(newRoleIds, role.ID)
+		report.Changes = append(report.Changes, AccessChange{
+			ResourceType: roleResourceType.Id,
+			Id:           strconv.Itoa(role.ID),
+			Name:         role.Name,
+		})
+	}
+
+	groups := newGroupBuilder(ctx, d.client, false, d.guardrails)
+	sourceGroups, err := groups.getUserGroupsResponse(ctx, sourceUserId)
+	if err != nil {
+		return nil, err
+	}
+	targetGroups, err := groups.getUserGroupsResponse(ctx, targetUserId)
+	if err != nil {
+		return nil, err
+	}
+	newGroupIds := make([]int, 0)
+	for _, group := range targetGroups.Group {
+		newGroupIds = append(newGroupIds, group.ID)
+	}
+	for _, group := range sourceGroups.Group {
+		if slices.Contains(newGroupIds, group.ID) {
+			continue
+		}
+		access := sodAccess{ResourceType: groupResourceType.Id, Id: strconv.Itoa(group.ID), Name: group.Name}
+		if !report.grantable(ctx, d.guardrails, d.guardrails.groups, &sodAccesses, access) {
+			continue
+		}
+		newGroupIds = append(newGroupIds, group.ID)
+		report.Changes = append(report.Changes, AccessChange{
+			ResourceType: groupResourceType.Id,
+			Id:           strconv.Itoa(group.ID),
+			Name:         group.Name,
+		})
+	}
+
+	// Content groups are not a synced resource type, so they have no allow or
+	// deny list nor separation-of-duties rules.
+	sourceContentGroups, err := getUserContentGroups(ctx, d.client, sourceUserId)
+	if err != nil {
+		return nil, err
+	}
+	targetContentGroups, err := getUserContentGroups(ctx, d.client, targetUserId)
+	if err != nil {
+		return nil, err
+	}
+	newContentGroupIds := make([]int, 0)
+	for _, contentGroup := range targetContentGroups.ContentGroups {
+		newContentGroupIds = append(newContentGroupIds, contentGroup.ID)
+	}
+	for _, contentGroup := range sourceContentGroups.ContentGroups {
+		if slices.Contains(newContentGroupIds, contentGroup.ID) {
+			continue
+		}
+		newContentGroupIds = append(newContentGroupIds, contentGroup.ID)
+		report.Changes = append(report.Changes, AccessChange{
+			ResourceType: contentGroupAccessType,
+			Id:           strconv.Itoa(contentGroup.ID),
+			Name:         contentGroup.Name,
+		})
+	}
+
+	sourceLicenses, err := getUserLicenses(ctx, d.client, sourceUserId)
+	if err != nil {
+		return nil, err
+	}
+	targetLicenses, err := getUserLicenses(ctx, d.client, targetUserId)
+	if err != nil {
+		return nil, err
+	}
+	newLicenses := make(map[string]bool)
+	for _, license := range coupaLicenses {
+		if !slices.Contains(sourceLicenses, license.ID) || slices.Contains(targetLicenses, license.ID) {
+			continue
+		}
+		access := sodAccess{ResourceType: licenseResourceType.Id, Id: license.ID, Name: license.Name}
+		if !report.grantable(ctx, d.guardrails, d.guardrails.licenses, &sodAccesses, access) {
+			continue
+		}
+		newLicenses[license.ID] = true
+		report.Changes = append(report.Changes, AccessChange{
+			ResourceType: licenseResourceType.Id,
+			Id:           license.ID,
+			Name:         license.Name,
+		})
+	}
+
+	if dryRun {
+		return report, nil
+	}
+
+	if len(newRoleIds) != len(targetRoles.Roles) {
+		userResponse, _, err := d.client.SetRoles(ctx, targetUserId, newRoleIds)
+		if err != nil {
+			return report, err
+		}
+		if len(userResponse.Roles) != len(newRoleIds) {
+			return report, errors.New("baton-coupa: roles not set")
+		}
+	}
+
+	if len(newGroupIds) != len(targetGroups.Group) {
+		userResponse, _, err := d.client.SetUserGroups(ctx, targetUserId, newGroupIds)
+		if err != nil {
+			return report, err
+		}
+		if len(userResponse.Group) != len(newGroupIds) {
+			return report, errors.New("baton-coupa: failed to add groups to user")
+		}
+	}
+
+	if len(newContentGroupIds) != len(targetContentGroups.ContentGroups) {
+		userResponse, _, err := d.client.SetContentGroups(ctx, targetUserId, newContentGroupIds)
+		if err != nil {
+			return report, err
+		}
+		if len(userResponse.ContentGroups) != len(newContentGroupIds) {
+			return report, errors.New("baton-coupa: failed to add content groups to user")
+		}
+	}
+
+	if len(newLicenses) != 0 {
+		userResponse, err := d.client.SetLicenses(ctx, targetUserId, newLicenses)
+		if err != nil {
+			return report, err
+		}
+
+		// Coupa drops the licenses it cannot assign, such as when none are
+		// left, without an error.
+		assigned, err := userResponse.Assigned()
+		if err != nil {
+			return report, err
+		}
+		missing := missingLicenses(newLicenses, assigned)
+		if len(missing) != 0 {
+			return report, fmt.Errorf("baton-coupa: licenses were not assigned: %s", strings.Join(missing, ", "))
+		}
+	}
+
+	return report, nil
+}
+
+// missingLicenses returns the sorted licenses that were asked for but are
+// not among the assigned ones.
+func missingLicenses(requested map[string]bool, assigned []string) []string {
+	missing := make([]string, 0)
+	for licenseId := range requested {
+		if !slices.Contains(assigned, licenseId) {
+			missing = append(missing, licenseId)
+		}
+	}
+	slices.Sort(missing)
+	return missing
+}
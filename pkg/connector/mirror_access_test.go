@@ -0,0 +1,47 @@
+package connector
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMirrorAccessReportGrantable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sod.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(testSodRules), 0o600))
+	rules, err := loadSodRules(path)
+	require.NoError(t, err)
+
+	g := &guardrails{
+		roles: accessList{denied: []string{"3"}},
+		sod:   rules,
+	}
+	report := &MirrorAccessReport{TargetUserId: 9}
+	accesses := []sodAccess{}
+
+	supplierManager := sodAccess{ResourceType: roleResourceType.Id, Id: "1", Name: "Supplier Manager"}
+	invoiceApprover := sodAccess{ResourceType: roleResourceType.Id, Id: "2", Name: "AP Invoice Approver"}
+	denied := sodAccess{ResourceType: roleResourceType.Id, Id: "3", Name: "Admin"}
+
+	require.True(t, report.grantable(context.Background(), g, g.roles, &accesses, supplierManager))
+	// The second role conflicts with one mirrored before it.
+	require.False(t, report.grantable(context.Background(), g, g.roles, &accesses, invoiceApprover))
+	require.False(t, report.grantable(context.Background(), g, g.roles, &accesses, denied))
+	require.Equal(t, []sodAccess{supplierManager}, accesses)
+	require.Len(t, report.Skipped, 2)
+	require.Contains(t, report.Skipped[0], "supplier-manager-invoice-approver")
+
+	// Warning rules do not skip the access.
+	accesses = []sodAccess{{ResourceType: approvalLimitResourceType.Id, Id: "7"}}
+	expenseUser := sodAccess{ResourceType: licenseResourceType.Id, Id: "expense-user"}
+	require.True(t, report.grantable(context.Background(), g, g.licenses, &accesses, expenseUser))
+}
+
+func TestMissingLicenses(t *testing.T) {
+	requested := map[string]bool{"expense-user": true, "purchasing-user": true, "contracts-user": true}
+	require.Empty(t, missingLicenses(requested, []string{"contracts-user", "expense-user", "purchasing-user"}))
+	require.Equal(t, []string{"contracts-user", "purchasing-user"}, missingLicenses(requested, []string{"expense-user"}))
+}
@@ -0,0 +1,175 @@
+package connector
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/conductorone/baton-coupa/pkg/connector/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	resourceSdk "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+type oauthClientBuilder struct {
+	client *client.Client
+}
+
+func (o *oauthClientBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return oauthClientResourceType
+}
+
+const oauthClientScopesProfileField = "scopes"
+
+// Create a new connector resource for a Coupa OAuth client. The scopes of
+// the client are kept in the profile, for Grants to read back.
+func oauthClientResource(oauthClient *client.OAuthClient, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	scopes := make([]interface{}, 0, len(oauthClient.Scopes))
+	for _, scope := range oauthClient.Scopes {
+		scopes = append(scopes, scope)
+	}
+
+	status := v2.UserTrait_Status_STATUS_DISABLED
+	if oauthClient.Active {
+		status = v2.UserTrait_Status_STATUS_ENABLED
+	}
+
+	return resourceSdk.NewUserResource(
+		oauthClient.Name,
+		oauthClientResourceType,
+		oauthClient.ID,
+		[]resourceSdk.UserTraitOption{
+			resourceSdk.WithStatus(status),
+			resourceSdk.WithAccountType(v2.UserTrait_ACCOUNT_TYPE_SERVICE),
+			resourceSdk.WithUserProfile(
+				map[string]interface{}{
+					"id":         oauthClient.ID,
+					"name":       oauthClient.Name,
+					"client_id":  oauthClient.ClientId,
+					"grant_type": oauthClient.GrantType,
+					"active":     oauthClient.Active,
+
+					oauthClientScopesProfileField: scopes,
+				}),
+			resourceSdk.WithUserLogin(oauthClient.ClientId),
+		},
+		resourceSdk.WithParentResourceID(parentResourceID),
+	)
+}
+
+func (o *oauthClientBuilder) List(
+	ctx context.Context,
+	parentResourceID *v2.ResourceId,
+	pToken *pagination.Token,
+) (
+	[]*v2.Resource,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	logger := ctxzap.Extract(ctx)
+	logger.Debug("Starting OAuth Clients List", zap.String("token", pToken.Token))
+
+	outputResources := make([]*v2.Resource, 0)
+	var outputAnnotations annotations.Annotations
+
+	var target client.OAuthClientsQueryResponse
+	response, ratelimitData, err := o.client.Query(
+		ctx,
+		client.OAuthClientsQuery(pToken.Token),
+		&target,
+	)
+	outputAnnotations.WithRateLimiting(ratelimitData)
+	if err != nil {
+		return nil, "", outputAnnotations, err
+	}
+	defer response.Body.Close()
+
+	lastId := ""
+	for _, oauthClient := range target.OAuthClients {
+		resource, err := oauthClientResource(oauthClient, parentResourceID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		outputResources = append(outputResources, resource)
+		lastId = strconv.Itoa(oauthClient.ID)
+	}
+
+	return outputResources, lastId, outputAnnotations, nil
+}
+
+// Entitlements always returns an empty slice for OAuth clients.
+func (o *oauthClientBuilder) Entitlements(
+	_ context.Context,
+	_ *v2.Resource,
+	_ *pagination.Token,
+) (
+	[]*v2.Entitlement,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	return nil, "", nil, nil
+}
+
+// Grants returns a read-only grant of each scope the OAuth client holds. The
+// scopes are read from the profile, so no request is made.
+func (o *oauthClientBuilder) Grants(
+	_ context.Context,
+	resource *v2.Resource,
+	_ *pagination.Token,
+) (
+	[]*v2.Grant,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	return heldPermissionGrants(
+		resource,
+		oauthClientScopesProfileField,
+		scopeResourceType,
+		scopeGrantedEntitlementName,
+	), "", nil, nil
+}
+
+// heldPermissionGrants returns a read-only grant to the service account of
+// each permission listed in the given field of its profile.
+func heldPermissionGrants(
+	principal *v2.Resource,
+	profileField string,
+	permissionResourceType *v2.ResourceType,
+	entitlementName string,
+) []*v2.Grant {
+	userTrait, err := resourceSdk.GetUserTrait(principal)
+	if err != nil || userTrait.GetProfile() == nil {
+		return nil
+	}
+
+	outputGrants := make([]*v2.Grant, 0)
+	for _, permission := range userTrait.GetProfile().GetFields()[profileField].GetListValue().GetValues() {
+		outputGrants = append(
+			outputGrants,
+			grant.NewGrant(
+				&v2.Resource{
+					Id: &v2.ResourceId{
+						ResourceType: permissionResourceType.Id,
+						Resource:     permission.GetStringValue(),
+					},
+				},
+				entitlementName,
+				principal.Id,
+				grant.WithAnnotation(&v2.GrantImmutable{}),
+			),
+		)
+	}
+	return outputGrants
+}
+
+func newOAuthClientBuilder(ctx context.Context, client *client.Client) *oauthClientBuilder {
+	return &oauthClientBuilder{
+		client: client,
+	}
+}
@@ -0,0 +1,23 @@
+package connector
+
+import (
+	"testing"
+
+	"github.com/conductorone/baton-coupa/pkg/connector/client"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOAuthClientGrants(t *testing.T) {
+	resource, err := oauthClientResource(&client.OAuthClient{
+		ID:       4,
+		Name:     "integration",
+		ClientId: "abc",
+		Scopes:   []string{"core.user.read", "core.user.write"},
+	}, nil)
+	require.NoError(t, err)
+
+	grants := heldPermissionGrants(resource, oauthClientScopesProfileField, scopeResourceType, scopeGrantedEntitlementName)
+	require.Len(t, grants, 2)
+	require.Equal(t, "scope:core.user.write:granted", grants[1].Entitlement.Id)
+	require.Equal(t, "4", grants[1].Principal.Id.Resource)
+}
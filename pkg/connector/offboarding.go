@@ -0,0 +1,545 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/conductorone/baton-coupa/pkg/connector/client"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+type OffboardingStepStatus string
+
+const (
+	OffboardingStepDone    OffboardingStepStatus = "done"
+	OffboardingStepSkipped OffboardingStepStatus = "skipped"
+	OffboardingStepFailed  OffboardingStepStatus = "failed"
+)
+
+type OffboardingStep struct {
+	Name   string                `json:"name"`
+	Status OffboardingStepStatus `json:"status"`
+	Detail string                `json:"detail,omitempty"`
+}
+
+// OffboardingReport is the result of each step of a user offboarding.
+type OffboardingReport struct {
+	UserId      int               `json:"user_id"`
+	SuccessorId int               `json:"successor_id,omitempty"`
+	Steps       []OffboardingStep `json:"steps"`
+}
+
+func (r *OffboardingReport) add(name string, status OffboardingStepStatus, detail string) {
+	r.Steps = append(r.Steps, OffboardingStep{Name: name, Status: status, Detail: detail})
+}
+
+func (r *OffboardingReport) fail(name string, err error) {
+	r.add(name, OffboardingStepFailed, err.Error())
+}
+
+// Failed reports whether any step of the offboarding failed.
+func (r *OffboardingReport) Failed() bool {
+	for _, step := range r.Steps {
+		if step.Status == OffboardingStepFailed {
+			return true
+		}
+	}
+	return false
+}
+
+// OffboardUser reassigns the pending approvals and open documents of a user
+// to a successor, removes all of the user's roles, user groups, content
+// groups, approval groups, approval limits, licenses, warehouses and
+// department, ends their delegations and deactivates them. Every step checks
+// the current state first, so a failed offboarding can be retried. The user is
+// only deactivated when every other step succeeded.
+func (d *Connector) OffboardUser(ctx context.Context, userId int, successorId int) (*OffboardingReport, error) {
+	l := ctxzap.Extract(ctx)
+	l.Info("baton-coupa: offboarding user", zap.Int("user_id", userId), zap.Int("successor_id", successorId))
+
+	if successorId == userId {
+		return nil, errors.New("baton-coupa: the successor must be another user")
+	}
+
+	err := d.guardrails.checkUser(strconv.Itoa(userId))
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = client.WithJournalTask(ctx, client.JournalTask{
+		Operation: "offboard",
+		Principal: strconv.Itoa(userId),
+	})
+
+	report := &OffboardingReport{
+		UserId:      userId,
+		SuccessorId: successorId,
+	}
+
+	user, err := getUser(ctx, d.client, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	d.reassignDocuments(ctx, report, userId, successorId)
+	d.removeRoles(ctx, report, userId)
+	d.removeGroups(ctx, report, userId)
+	d.removeContentGroups(ctx, report, userId)
+	d.removeApprovalGroups(ctx, report, userId)
+	d.removeApprovalLimits(ctx, report, userId)
+	d.removeLicenses(ctx, report, userId)
+	d.removeWarehouses(ctx, report, userId)
+	d.removeDepartment(ctx, report, userId)
+	d.endDelegations(ctx, report, userId)
+
+	switch {
+	case report.Failed():
+		report.add("deactivate", OffboardingStepSkipped, "a previous step failed")
+	case !user.Active:
+		report.add("deactivate", OffboardingStepSkipped, "user is already inactive")
+	default:
+		d.deactivate(ctx, report, userId)
+	}
+
+	if report.Failed() {
+		return report, errors.New("baton-coupa: offboarding did not complete")
+	}
+
+	return report, nil
+}
+
+// deactivate deactivates the user, unless they hold a critical role that would
+// be left without enough active holders.
+func (d *Connector) deactivate(ctx context.Context, report *OffboardingReport, userId int) {
+	const step = "deactivate"
+
+	user, err := newRoleBuilder(ctx, d.client, d.guardrails, nil).getUserRoles(ctx, userId)
+	if err != nil {
+		report.fail(step, err)
+		return
+	}
+
+	err = d.guardrails.checkCriticalRoles(ctx, d.client, userId, user.Roles)
+	if err != nil {
+		report.fail(step, err)
+		return
+	}
+
+	userResponse, _, err := d.client.SetUserActive(ctx, userId, false)
+	switch {
+	case err != nil:
+		report.fail(step, err)
+	case userResponse.Active:
+		report.fail(step, errors.New("user is still active"))
+	default:
+		report.add(step, OffboardingStepDone, "")
+	}
+}
+
+// reassignDocuments assigns the pending approvals and open requisitions,
+// invoices and contracts of the user to the successor. The step fails while
+// any of them is still assigned to the user.
+func (d *Connector) reassignDocuments(ctx context.Context, report *OffboardingReport, userId int, successorId int) {
+	const step = "reassign_documents"
+
+	documents, err := getUserDocuments(ctx, d.client, userId)
+	if err != nil {
+		report.fail(step, err)
+		return
+	}
+
+	counts := make([]string, 0)
+	for _, documentType := range client.DocumentTypes {
+		if len(documents[documentType]) > 0 {
+			counts = append(counts, fmt.Sprintf("%d %s", len(documents[documentType]), documentType))
+		}
+	}
+	if len(counts) == 0 {
+		report.add(step, OffboardingStepSkipped, "no pending approvals or open documents")
+		return
+	}
+
+	if successorId == 0 {
+		report.fail(step, fmt.Errorf("%s need a successor", strings.Join(counts, ", ")))
+		return
+	}
+
+	successor, err := getUser(ctx, d.client, successorId)
+	if err != nil {
+		report.fail(step, err)
+		return
+	}
+	if !successor.Active {
+		report.fail(step, fmt.Errorf("successor %d is not active", successorId))
+		return
+	}
+
+	for _, documentType := range client.DocumentTypes {
+		for _, document := range documents[documentType] {
+			owner, _, err := d.client.ReassignDocument(ctx, documentType, document.ID, successorId)
+			if err != nil {
+				report.fail(step, fmt.Errorf("%s %d: %w", documentType, document.ID, err))
+				return
+			}
+			if owner == nil || owner.Id != successorId {
+				report.fail(step, fmt.Errorf("%s %d was not reassigned", documentType, document.ID))
+				return
+			}
+		}
+	}
+
+	report.add(step, OffboardingStepDone, fmt.Sprintf("reassigned %s", strings.Join(counts, ", ")))
+}
+
+func (d *Connector) removeRoles(ctx context.Context, report *OffboardingReport, userId int) {
+	const step = "remove_roles"
+
+	user, err := newRoleBuilder(ctx, d.client, d.guardrails, nil).getUserRoles(ctx, userId)
+	if err != nil {
+		report.fail(step, err)
+		return
+	}
+
+	if len(user.Roles) == 0 {
+		report.add(step, OffboardingStepSkipped, "user has no roles")
+		return
+	}
+
+	err = d.guardrails.checkCriticalRoles(ctx, d.client, userId, user.Roles)
+	if err != nil {
+		report.fail(step, err)
+		return
+	}
+
+	userResponse, _, err := d.client.SetRoles(ctx, userId, make([]int, 0))
+	if err != nil {
+		report.fail(step, err)
+		return
+	}
+
+	if len(userResponse.Roles) != 0 {
+		report.fail(step, errors.New("roles were not removed"))
+		return
+	}
+
+	report.add(step, OffboardingStepDone, fmt.Sprintf("removed %d roles", len(user.Roles)))
+}
+
+func (d *Connector) removeGroups(ctx context.Context, report *OffboardingReport, userId int) {
+	const step = "remove_groups"
+
+	user, err := newGroupBuilder(ctx, d.client, false, d.guardrails).getUserGroupsResponse(ctx, userId)
+	if err != nil {
+		report.fail(step, err)
+		return
+	}
+
+	if len(user.Group) == 0 {
+		report.add(step, OffboardingStepSkipped, "user has no groups")
+		return
+	}
+
+	userResponse, _, err := d.client.SetUserGroups(ctx, userId, make([]int, 0))
+	if err != nil {
+		report.fail(step, err)
+		return
+	}
+
+	if len(userResponse.Group) != 0 {
+		report.fail(step, errors.New("groups were not removed"))
+		return
+	}
+
+	report.add(step, OffboardingStepDone, fmt.Sprintf("removed %d groups", len(user.Group)))
+}
+
+func (d *Connector) removeContentGroups(ctx context.Context, report *OffboardingReport, userId int) {
+	const step = "remove_content_groups"
+
+	user, err := getUserContentGroups(ctx, d.client, userId)
+	if err != nil {
+		report.fail(step, err)
+		return
+	}
+
+	if len(user.ContentGroups) == 0 {
+		report.add(step, OffboardingStepSkipped, "user has no content groups")
+		return
+	}
+
+	userResponse, _, err := d.client.SetContentGroups(ctx, userId, make([]int, 0))
+	if err != nil {
+		report.fail(step, err)
+		return
+	}
+
+	if len(userResponse.ContentGroups) != 0 {
+		report.fail(step, errors.New("content groups were not removed"))
+		return
+	}
+
+	report.add(step, OffboardingStepDone, fmt.Sprintf("removed %d content groups", len(user.ContentGroups)))
+}
+
+// removeApprovalGroups removes the user from every approval group, keeping
+// the other members.
+func (d *Connector) removeApprovalGroups(ctx context.Context, report *OffboardingReport, userId int) {
+	const step = "remove_approval_groups"
+
+	memberIds := make(map[int][]int)
+	approvalGroupIds := make([]int, 0)
+	lastId := ""
+	for {
+		var target client.ApprovalGroupMembersQueryResponse
+		response, _, err := d.client.Query(ctx, client.UserApprovalGroupsQuery(userId, lastId), &target)
+		if err != nil {
+			report.fail(step, err)
+			return
+		}
+		response.Body.Close()
+
+		if len(target.ApprovalGroups) == 0 {
+			break
+		}
+
+		for _, approvalGroup := range target.ApprovalGroups {
+			approvalGroupIds = append(approvalGroupIds, approvalGroup.Id)
+			for _, member := range approvalGroup.Users {
+				if member.Id != userId {
+					memberIds[approvalGroup.Id] = append(memberIds[approvalGroup.Id], member.Id)
+				}
+			}
+			lastId = strconv.Itoa(approvalGroup.Id)
+		}
+	}
+
+	if len(approvalGroupIds) == 0 {
+		report.add(step, OffboardingStepSkipped, "user is in no approval groups")
+		return
+	}
+
+	for _, approvalGroupId := range approvalGroupIds {
+		newMemberIds := memberIds[approvalGroupId]
+		if newMemberIds == nil {
+			newMemberIds = make([]int, 0)
+		}
+
+		approvalGroup, _, err := d.client.SetApprovalGroupUsers(ctx, approvalGroupId, newMemberIds)
+		if err != nil {
+			report.fail(step, fmt.Errorf("approval group %d: %w", approvalGroupId, err))
+			return
+		}
+
+		if len(approvalGroup.Users) != len(newMemberIds) {
+			report.fail(step, fmt.Errorf("user was not removed from approval group %d", approvalGroupId))
+			return
+		}
+	}
+
+	report.add(step, OffboardingStepDone, fmt.Sprintf("removed from %d approval groups", len(approvalGroupIds)))
+}
+
+// removeApprovalLimits clears both the requisition and the expense approval
+// limits of the user.
+func (d *Connector) removeApprovalLimits(ctx context.Context, report *OffboardingReport, userId int) {
+	const step = "remove_approval_limits"
+
+	user, err := newApprovalLimitBuilder(ctx, d.client, d.guardrails).getUserApprovalLimits(ctx, userId)
+	if err != nil {
+		report.fail(step, err)
+		return
+	}
+
+	removed := 0
+	for _, limitType := range []client.ApprovalLimitType{client.ApprovalLimitTypeRequisition, client.ApprovalLimitTypeExpense} {
+		if limitType.Of(user) == nil {
+			continue
+		}
+
+		userResponse, _, err := d.client.SetApprovalLimit(ctx, userId, limitType, nil)
+		if err != nil {
+			report.fail(step, fmt.Errorf("%s: %w", limitType, err))
+			return
+		}
+
+		if limitType.Of(userResponse.UserApprovalLimits()) != nil {
+			report.fail(step, fmt.Errorf("%s was not removed", limitType))
+			return
+		}
+		removed++
+	}
+
+	if removed == 0 {
+		report.add(step, OffboardingStepSkipped, "user has no approval limits")
+		return
+	}
+
+	report.add(step, OffboardingStepDone, fmt.Sprintf("removed %d approval limits", removed))
+}
+
+func (d *Connector) removeLicenses(ctx context.Context, report *OffboardingReport, userId int) {
+	const step = "remove_licenses"
+
+	assigned, err := getUserLicenses(ctx, d.client, userId)
+	if err != nil {
+		report.fail(step, err)
+		return
+	}
+
+	if len(assigned) == 0 {
+		report.add(step, OffboardingStepSkipped, "user has no licenses")
+		return
+	}
+
+	licenses := make(map[string]bool)
+	for _, licenseId := range assigned {
+		licenses[licenseId] = false
+	}
+
+	userResponse, err := d.client.SetLicenses(ctx, userId, licenses)
+	if err != nil {
+		report.fail(step, err)
+		return
+	}
+
+	remaining, err := userResponse.Assigned()
+	if err != nil {
+		report.fail(step, err)
+		return
+	}
+	if len(remaining) != 0 {
+		report.fail(step, fmt.Errorf("licenses were not removed: %s", strings.Join(remaining, ", ")))
+		return
+	}
+
+	report.add(step, OffboardingStepDone, fmt.Sprintf("removed %d licenses", len(assigned)))
+}
+
+func (d *Connector) removeWarehouses(ctx context.Context, report *OffboardingReport, userId int) {
+	const step = "remove_warehouses"
+
+	warehouseIds, err := newWarehouseBuilder(ctx, d.client, d.guardrails).getUserWarehouses(ctx, userId)
+	if err != nil {
+		report.fail(step, err)
+		return
+	}
+
+	if len(warehouseIds) == 0 {
+		report.add(step, OffboardingStepSkipped, "user has no warehouses")
+		return
+	}
+
+	userResponse, _, err := d.client.SetUserWarehouses(ctx, userId, make([]int, 0))
+	if err != nil {
+		report.fail(step, err)
+		return
+	}
+
+	if len(userResponse.Warehouses) != 0 {
+		report.fail(step, errors.New("warehouses were not removed"))
+		return
+	}
+
+	report.add(step, OffboardingStepDone, fmt.Sprintf("removed %d warehouses", len(warehouseIds)))
+}
+
+func (d *Connector) removeDepartment(ctx context.Context, report *OffboardingReport, userId int) {
+	const step = "remove_department"
+
+	user, err := newDepartmentBuilder(ctx, d.client, d.guardrails).getUserDepartment(ctx, userId)
+	if err != nil {
+		report.fail(step, err)
+		return
+	}
+
+	if user.Department == nil {
+		report.add(step, OffboardingStepSkipped, "user has no department")
+		return
+	}
+
+	userResponse, _, err := d.client.SetUserDepartment(ctx, userId, nil)
+	if err != nil {
+		report.fail(step, err)
+		return
+	}
+
+	if userResponse.Department != nil {
+		report.fail(step, errors.New("department was not removed"))
+		return
+	}
+
+	report.add(step, OffboardingStepDone, fmt.Sprintf("removed from department %d", user.Department.Id))
+}
+
+func (d *Connector) endDelegations(ctx context.Context, report *OffboardingReport, userId int) {
+	const step = "end_delegations"
+
+	now := time.Now()
+	id := strconv.Itoa(userId)
+	queries := []func(pg string) string{
+		func(pg string) string { return client.DelegationsQuery(id, now, pg) },
+		func(pg string) string { return client.DelegatedToQuery(id, now, pg) },
+	}
+
+	delegationIds := make([]int, 0)
+	for _, query := range queries {
+		lastId := ""
+		for {
+			var target client.DelegationsQueryResponse
+			response, _, err := d.client.Query(ctx, query(lastId), &target)
+			if err != nil {
+				report.fail(step, err)
+				return
+			}
+			response.Body.Close()
+
+			if len(target.Delegations) == 0 {
+				break
+			}
+
+			for _, delegation := range target.Delegations {
+				delegationIds = append(delegationIds, delegation.ID)
+				lastId = strconv.Itoa(delegation.ID)
+			}
+		}
+	}
+
+	if len(delegationIds) == 0 {
+		report.add(step, OffboardingStepSkipped, "user has no active delegations")
+		return
+	}
+
+	for _, delegationId := range delegationIds {
+		_, err := d.client.DeleteDelegation(ctx, delegationId)
+		if err != nil {
+			report.fail(step, fmt.Errorf("delegation %d: %w", delegationId, err))
+			return
+		}
+	}
+
+	report.add(step, OffboardingStepDone, fmt.Sprintf("ended %d delegations", len(delegationIds)))
+}
+
+func getUser(ctx context.Context, coupaClient *client.Client, userId int) (*client.User, error) {
+	var target client.UsersQueryResponse
+	response, _, err := coupaClient.Query(
+		ctx,
+		client.GetUser(userId),
+		&target,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if len(target.Users) == 0 {
+		return nil, fmt.Errorf("baton-coupa: user %d not found", userId)
+	}
+
+	return target.Users[0], nil
+}
@@ -0,0 +1,89 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/conductorone/baton-coupa/pkg/connector/client"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+// PcardDeactivationReport is the result of a p-card deactivation. Deactivated
+// is false when the card was already inactive.
+type PcardDeactivationReport struct {
+	PcardId     int    `json:"pcard_id"`
+	Card        string `json:"card"`
+	HolderId    int    `json:"holder_id,omitempty"`
+	Deactivated bool   `json:"deactivated"`
+}
+
+// DeactivatePcard deactivates a p-card, such as the card of an offboarded
+// user. The cards of protected users are left untouched.
+func (d *Connector) DeactivatePcard(ctx context.Context, pcardId int) (*PcardDeactivationReport, error) {
+	l := ctxzap.Extract(ctx)
+	l.Info("baton-coupa: deactivating p-card", zap.Int("pcard_id", pcardId))
+
+	pcard, err := getPcard(ctx, d.client, pcardId)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &PcardDeactivationReport{
+		PcardId: pcardId,
+		Card:    maskCardNumber(pcard.Number),
+	}
+	principal := ""
+	if pcard.User != nil {
+		report.HolderId = pcard.User.Id
+		principal = strconv.Itoa(pcard.User.Id)
+
+		err = d.guardrails.checkUser(principal)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !pcard.Active {
+		return report, nil
+	}
+
+	ctx = client.WithJournalTask(ctx, client.JournalTask{
+		Operation:    "deactivate_pcard",
+		ResourceType: pcardResourceType.Id,
+		ResourceId:   strconv.Itoa(pcardId),
+		Principal:    principal,
+	})
+
+	pcardResponse, _, err := d.client.SetPcardActive(ctx, pcardId, false)
+	if err != nil {
+		return report, err
+	}
+	if pcardResponse.Active {
+		return report, errors.New("baton-coupa: p-card is still active")
+	}
+
+	report.Deactivated = true
+	return report, nil
+}
+
+func getPcard(ctx context.Context, coupaClient *client.Client, pcardId int) (*client.Pcard, error) {
+	var target client.PcardsQueryResponse
+	response, _, err := coupaClient.Query(
+		ctx,
+		client.PcardQuery(strconv.Itoa(pcardId)),
+		&target,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if len(target.Pcards) == 0 {
+		return nil, fmt.Errorf("baton-coupa: p-card %d not found", pcardId)
+	}
+
+	return target.Pcards[0], nil
+}
@@ -0,0 +1,218 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/conductorone/baton-coupa/pkg/connector/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	resourceSdk "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const pcardHolderEntitlementName = "holder"
+
+type pcardBuilder struct {
+	client *client.Client
+}
+
+func (o *pcardBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return pcardResourceType
+}
+
+// maskCardNumber keeps only the last four digits of a card number, in case
+// the API returns more than a masked number.
+func maskCardNumber(number string) string {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, number)
+	if len(digits) > 4 {
+		digits = digits[len(digits)-4:]
+	}
+	return fmt.Sprintf("****%s", digits)
+}
+
+// pcardHolder is kept in an annotation of the p-card resource when List loads
+// the card, so Grants does not fetch the card again.
+func pcardHolder(pcard *client.Pcard) (*structpb.Struct, error) {
+	holder := map[string]interface{}{
+		"active": pcard.Active,
+		"expiry": pcard.Expiry,
+	}
+	if pcard.User != nil {
+		holder["holder_id"] = strconv.Itoa(pcard.User.Id)
+	}
+	return structpb.NewStruct(holder)
+}
+
+func pcardResource(pcard *client.Pcard, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	holder, err := pcardHolder(pcard)
+	if err != nil {
+		return nil, err
+	}
+
+	maskedNumber := maskCardNumber(pcard.Number)
+
+	name := fmt.Sprintf("%s %s", pcard.Name, maskedNumber)
+	if pcard.Name == "" {
+		name = fmt.Sprintf("%s %s", pcard.CardType, maskedNumber)
+	}
+
+	details := []string{fmt.Sprintf("card %s", maskedNumber)}
+	if pcard.Limit != nil {
+		currency := ""
+		if pcard.Currency != nil {
+			currency = pcard.Currency.Code
+		}
+		details = append(details, strings.TrimSpace(fmt.Sprintf("limit %s %s", *pcard.Limit, currency)))
+	}
+	if pcard.Expiry != "" {
+		details = append(details, fmt.Sprintf("expires %s", pcard.Expiry))
+	}
+	if !pcard.Active {
+		details = append(details, "inactive")
+	}
+
+	return resourceSdk.NewResource(
+		strings.TrimSpace(name),
+		pcardResourceType,
+		pcard.ID,
+		resourceSdk.WithParentResourceID(parentResourceID),
+		resourceSdk.WithAnnotation(holder),
+		resourceSdk.WithDescription(
+			fmt.Sprintf("P-card in Coupa: %s", strings.Join(details, ", ")),
+		),
+	)
+}
+
+func (o *pcardBuilder) List(
+	ctx context.Context,
+	parentResourceID *v2.ResourceId,
+	pToken *pagination.Token,
+) (
+	[]*v2.Resource,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	logger := ctxzap.Extract(ctx)
+	logger.Debug("Starting P-cards List", zap.String("token", pToken.Token))
+
+	outputResources := make([]*v2.Resource, 0)
+	var outputAnnotations annotations.Annotations
+
+	var target client.PcardsQueryResponse
+	response, ratelimitData, err := o.client.Query(
+		ctx,
+		client.PcardsQuery(pToken.Token),
+		&target,
+	)
+	outputAnnotations.WithRateLimiting(ratelimitData)
+	if err != nil {
+		return nil, "", outputAnnotations, err
+	}
+	defer response.Body.Close()
+
+	lastId := ""
+	for _, pcard := range target.Pcards {
+		resource, err := pcardResource(pcard, parentResourceID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		outputResources = append(outputResources, resource)
+		lastId = strconv.Itoa(pcard.ID)
+	}
+
+	return outputResources, lastId, outputAnnotations, nil
+}
+
+func (o *pcardBuilder) Entitlements(
+	_ context.Context,
+	resource *v2.Resource,
+	_ *pagination.Token,
+) (
+	[]*v2.Entitlement,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	return []*v2.Entitlement{
+		entitlement.NewAssignmentEntitlement(
+			resource,
+			pcardHolderEntitlementName,
+			entitlement.WithGrantableTo(userResourceType),
+			entitlement.WithDisplayName(
+				fmt.Sprintf("%s Holder", resource.DisplayName),
+			),
+			entitlement.WithDescription(
+				fmt.Sprintf("Holder of the %s p-card in Coupa", resource.DisplayName),
+			),
+			entitlement.WithAnnotation(&v2.EntitlementImmutable{}),
+		),
+	}, "", nil, nil
+}
+
+// Grants returns a read-only holder grant for the user the card is assigned
+// to, read from the annotation set by List.
+func (o *pcardBuilder) Grants(
+	_ context.Context,
+	resource *v2.Resource,
+	_ *pagination.Token,
+) (
+	[]*v2.Grant,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	holder := &structpb.Struct{}
+	annos := annotations.Annotations(resource.Annotations)
+	ok, err := annos.Pick(holder)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if !ok {
+		return nil, "", nil, errors.New("baton-coupa: p-card without holder annotation")
+	}
+
+	fields := holder.GetFields()
+	holderId := fields["holder_id"].GetStringValue()
+	if holderId == "" {
+		return nil, "", nil, nil
+	}
+
+	return []*v2.Grant{
+		grant.NewGrant(
+			resource,
+			pcardHolderEntitlementName,
+			&v2.ResourceId{
+				ResourceType: userResourceType.Id,
+				Resource:     holderId,
+			},
+			grant.WithAnnotation(&v2.GrantImmutable{}),
+			grant.WithGrantMetadata(
+				map[string]interface{}{
+					"active": fields["active"].GetBoolValue(),
+					"expiry": fields["expiry"].GetStringValue(),
+				},
+			),
+		),
+	}, "", nil, nil
+}
+
+func newPcardBuilder(ctx context.Context, client *client.Client) *pcardBuilder {
+	return &pcardBuilder{
+		client: client,
+	}
+}
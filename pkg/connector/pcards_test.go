@@ -0,0 +1,29 @@
+package connector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/conductorone/baton-coupa/pkg/connector/client"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPcardGrants(t *testing.T) {
+	builder := &pcardBuilder{}
+
+	resource, err := pcardResource(&client.Pcard{ID: 3, Number: "4111111111111111", Active: true, User: &client.ResourceId{Id: 7}}, nil)
+	require.NoError(t, err)
+	require.Equal(t, "P-card in Coupa: card ****1111", resource.Description)
+
+	grants, _, _, err := builder.Grants(context.Background(), resource, &pagination.Token{})
+	require.NoError(t, err)
+	require.Len(t, grants, 1)
+	require.Equal(t, "7", grants[0].Principal.Id.Resource)
+
+	resource, err = pcardResource(&client.Pcard{ID: 4, Number: "4111111111112222"}, nil)
+	require.NoError(t, err)
+	grants, _, _, err = builder.Grants(context.Background(), resource, &pagination.Token{})
+	require.NoError(t, err)
+	require.Empty(t, grants)
+}
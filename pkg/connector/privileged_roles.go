@@ -0,0 +1,137 @@
+package connector
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/conductorone/baton-coupa/pkg/connector/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	resourceSdk "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const privilegedProfileField = "privileged"
+
+// sensitiveResources are the resources whose Coupa permissions make a role
+// privileged. Coupa names permissions after the resource and the action, as
+// in users#update, and namespaces resources with slashes, as in admin/users.
+var sensitiveResources = []string{
+	"admin",
+	"api_keys",
+	"approval_chains",
+	"approval_limits",
+	"business_entities",
+	"integrations",
+	"oauth2_clients",
+	"payments",
+	"roles",
+	"setup",
+	"user_groups",
+	"users",
+}
+
+// readActions are the actions that do not change anything. A permission on a
+// sensitive resource only makes a role privileged when it writes.
+var readActions = []string{
+	"index",
+	"show",
+	"search",
+}
+
+// isSensitivePermission tells whether a permission writes to a sensitive
+// resource, which must be one of the segments of the resource name so that
+// supplier_users does not match users. A permission without an action covers
+// every action.
+func isSensitivePermission(permission string) bool {
+	resource, action, _ := strings.Cut(strings.ToLower(permission), "#")
+	if slices.Contains(readActions, action) {
+		return false
+	}
+	for _, segment := range strings.Split(resource, "/") {
+		if slices.Contains(sensitiveResources, segment) {
+			return true
+		}
+	}
+	return false
+}
+
+// roleClassifier tells privileged roles apart. Roles can be marked privileged
+// or not by name in the configuration, otherwise they are classified by their
+// permissions.
+type roleClassifier struct {
+	privilegedRoles   []string
+	unprivilegedRoles []string
+	// permissionsUnavailable is set when the schema of the instance has no
+	// role permissions, so they are not asked for again.
+	permissionsUnavailable atomic.Bool
+}
+
+type roleClassification struct {
+	Privileged bool
+	Reasons    []string
+}
+
+func (c *roleClassifier) classify(role *client.Role) roleClassification {
+	if slices.Contains(c.unprivilegedRoles, role.Name) {
+		return roleClassification{Reasons: []string{"configured as not privileged"}}
+	}
+	if slices.Contains(c.privilegedRoles, role.Name) {
+		return roleClassification{Privileged: true, Reasons: []string{"configured as privileged"}}
+	}
+
+	reasons := make([]string, 0)
+	for _, permission := range role.Permissions {
+		if isSensitivePermission(permission) {
+			reasons = append(reasons, fmt.Sprintf("has the %s permission", permission))
+		}
+	}
+	return roleClassification{Privileged: len(reasons) > 0, Reasons: reasons}
+}
+
+// profile returns the role profile fields of the classification.
+func (c roleClassification) profile() map[string]interface{} {
+	reasons := make([]interface{}, 0, len(c.Reasons))
+	for _, reason := range c.Reasons {
+		reasons = append(reasons, reason)
+	}
+	return map[string]interface{}{
+		privilegedProfileField: c.Privileged,
+		"privileged_reasons":   reasons,
+	}
+}
+
+// riskAnnotation marks privileged roles and their entitlements, so reviews and
+// requests can apply a stricter policy to them.
+func riskAnnotation(reasons []string) (*structpb.Struct, error) {
+	rv := make([]interface{}, 0, len(reasons))
+	for _, reason := range reasons {
+		rv = append(rv, reason)
+	}
+	return structpb.NewStruct(map[string]interface{}{
+		"privileged": true,
+		"risk_level": "high",
+		"reasons":    rv,
+	})
+}
+
+// privilegedRoleReasons reads the classification back from a role resource,
+// and returns why the role is privileged.
+func privilegedRoleReasons(resource *v2.Resource) ([]string, bool) {
+	roleTrait, err := resourceSdk.GetRoleTrait(resource)
+	if err != nil || roleTrait.GetProfile() == nil {
+		return nil, false
+	}
+
+	fields := roleTrait.GetProfile().GetFields()
+	if !fields[privilegedProfileField].GetBoolValue() {
+		return nil, false
+	}
+
+	reasons := make([]string, 0)
+	for _, reason := range fields["privileged_reasons"].GetListValue().GetValues() {
+		reasons = append(reasons, reason.GetStringValue())
+	}
+	return reasons, true
+}
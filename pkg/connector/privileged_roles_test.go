@@ -0,0 +1,60 @@
+package connector
+
+import (
+	"testing"
+
+	"github.com/conductorone/baton-coupa/pkg/connector/client"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoleClassifier(t *testing.T) {
+	classifier := &roleClassifier{
+		privilegedRoles:   []string{"Admin"},
+		unprivilegedRoles: []string{"Buyer"},
+	}
+
+	testCases := []struct {
+		message    string
+		role       *client.Role
+		privileged bool
+	}{
+		{
+			message:    "configured privileged",
+			role:       &client.Role{Name: "Admin"},
+			privileged: true,
+		}, {
+			message: "configured unprivileged",
+			role:    &client.Role{Name: "Buyer", Permissions: []string{"roles#update"}},
+		}, {
+			message:    "sensitive permission",
+			role:       &client.Role{Name: "Integrator", Permissions: []string{"requisitions#index", "API_Keys#create"}},
+			privileged: true,
+		}, {
+			message: "no sensitive permission",
+			role:    &client.Role{Name: "Requester", Permissions: []string{"requisitions#create"}},
+		}, {
+			message: "read-only sensitive permissions",
+			role:    &client.Role{Name: "Auditor", Permissions: []string{"user_groups#index", "roles#show", "approval_limits#index"}},
+		}, {
+			message: "near misses",
+			role:    &client.Role{Name: "Supplier Manager", Permissions: []string{"supplier_users#update", "payment_terms#create", "roles_reports#create"}},
+		}, {
+			message:    "namespaced permission",
+			role:       &client.Role{Name: "Administrator", Permissions: []string{"admin/users#update"}},
+			privileged: true,
+		}, {
+			message:    "permission without action",
+			role:       &client.Role{Name: "Setup", Permissions: []string{"setup"}},
+			privileged: true,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.message, func(t *testing.T) {
+			classification := classifier.classify(testCase.role)
+			require.Equal(t, testCase.privileged, classification.Privileged)
+			if testCase.privileged {
+				require.NotEmpty(t, classification.Reasons)
+			}
+		})
+	}
+}
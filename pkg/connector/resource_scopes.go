@@ -0,0 +1,280 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/conductorone/baton-coupa/pkg/connector/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+// baseScopes are requested whatever resource types are synced.
+var baseScopes = []string{
+	"core.common.read",
+	"core.user.read",
+	"email login",
+	"openid",
+	"profile",
+}
+
+// offboardingRiskScopes are needed to count the documents assigned to users.
+var offboardingRiskScopes = []string{
+	"core.approval.read",
+	"core.contract.read",
+	"core.invoice.read",
+	"core.requisition.read",
+}
+
+// resourceTypeAccess is what a resource type needs to be synced: the scopes
+// to request, and a cheap query that fails when they were not granted.
+type resourceTypeAccess struct {
+	scopes []string
+	probe  func(d *Connector) string
+}
+
+var resourceTypeAccesses = map[string]resourceTypeAccess{
+	userResourceType.Id: {
+		scopes: []string{"core.user.read"},
+		probe:  func(d *Connector) string { return client.AllUsersQuery("") },
+	},
+	groupResourceType.Id: {
+		scopes: []string{"core.user_group.read"},
+		probe:  func(d *Connector) string { return client.GroupsQuery("") },
+	},
+	roleResourceType.Id: {
+		scopes: []string{"core.user.read"},
+		probe:  func(d *Connector) string { return client.RolesQuery("") },
+	},
+	licenseResourceType.Id: {
+		scopes: []string{"core.user.read"},
+		probe:  func(d *Connector) string { return client.AllUsersQuery("") },
+	},
+	approvalGroupResourceType.Id: {
+		scopes: []string{"core.approval.read"},
+		probe:  func(d *Connector) string { return client.ApprovalGroupsQuery("") },
+	},
+	approvalChainResourceType.Id: {
+		scopes: []string{"core.approval.read"},
+		probe:  func(d *Connector) string { return client.ApprovalChainsQuery("") },
+	},
+	approvalLimitResourceType.Id: {
+		scopes: []string{"core.approval.read"},
+		probe:  func(d *Connector) string { return client.ApprovalLimitsQuery("") },
+	},
+	businessEntityResourceType.Id: {
+		scopes: []string{"core.business_entity.read"},
+		probe:  func(d *Connector) string { return client.BusinessEntitiesQuery("") },
+	},
+	supplierResourceType.Id: {
+		scopes: []string{"core.supplier.read"},
+		probe:  func(d *Connector) string { return client.SuppliersQuery("") },
+	},
+	supplierUserResourceType.Id: {
+		scopes: []string{"core.supplier.read"},
+		probe:  func(d *Connector) string { return client.SuppliersQuery("") },
+	},
+	oauthClientResourceType.Id: {
+		scopes: []string{"core.common.read"},
+		probe:  func(d *Connector) string { return client.OAuthClientsQuery("") },
+	},
+	scopeResourceType.Id: {
+		scopes: []string{"core.common.read"},
+		probe:  func(d *Connector) string { return client.OAuthClientsQuery("") },
+	},
+	apiKeyResourceType.Id: {
+		scopes: []string{"core.common.read"},
+		probe:  func(d *Connector) string { return client.ApiKeysQuery("") },
+	},
+	apiPermissionResourceType.Id: {
+		scopes: []string{"core.common.read"},
+		probe:  func(d *Connector) string { return client.ApiKeysQuery("") },
+	},
+	pcardResourceType.Id: {
+		scopes: []string{"core.common.read"},
+		probe:  func(d *Connector) string { return client.PcardsQuery("") },
+	},
+	warehouseResourceType.Id: {
+		scopes: []string{"core.inventory.common.read"},
+		probe:  func(d *Connector) string { return client.WarehousesQuery("") },
+	},
+	budgetLineResourceType.Id: {
+		scopes: []string{"core.budget.read"},
+		probe:  func(d *Connector) string { return client.BudgetLinesQuery(d.budgetPeriod, "") },
+	},
+	departmentResourceType.Id: {
+		scopes: []string{"core.common.read"},
+		probe:  func(d *Connector) string { return client.DepartmentsQuery("") },
+	},
+}
+
+// requiredScopes returns the sorted scopes needed to sync the given resource
+// types with the given features.
+func requiredScopes(resourceTypes []string, syncOffboardingRisk bool) []string {
+	scopes := slices.Clone(baseScopes)
+	for _, resourceType := range resourceTypes {
+		scopes = append(scopes, resourceTypeAccesses[resourceType].scopes...)
+	}
+	if syncOffboardingRisk {
+		scopes = append(scopes, offboardingRiskScopes...)
+	}
+	slices.Sort(scopes)
+	return slices.Compact(scopes)
+}
+
+// validateResourceTypes checks that the configured resource types are known,
+// and defaults to all of them.
+func validateResourceTypes(resourceTypes []string) ([]string, error) {
+	if len(resourceTypes) == 0 {
+		resourceTypes = make([]string, 0, len(resourceTypeAccesses))
+		for resourceType := range resourceTypeAccesses {
+			resourceTypes = append(resourceTypes, resourceType)
+		}
+		slices.Sort(resourceTypes)
+		return resourceTypes, nil
+	}
+
+	for _, resourceType := range resourceTypes {
+		if _, ok := resourceTypeAccesses[resourceType]; !ok {
+			return nil, fmt.Errorf("baton-coupa: unknown resource type %s", resourceType)
+		}
+	}
+	// Users are the principals of every grant, they are always synced.
+	if !slices.Contains(resourceTypes, userResourceType.Id) {
+		resourceTypes = append(resourceTypes, userResourceType.Id)
+	}
+	return resourceTypes, nil
+}
+
+// disabledSyncers holds the resource types that cannot be synced, with the
+// reason why. It is filled by Validate, which runs before each sync.
+type disabledSyncers struct {
+	mu      sync.RWMutex
+	reasons map[string]string
+}
+
+func (s *disabledSyncers) set(reasons map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reasons = reasons
+}
+
+func (s *disabledSyncers) disabled(resourceType string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.reasons[resourceType]
+	return ok
+}
+
+// probeResourceTypes runs the probe query of each enabled resource type. The
+// resource types that fail for lack of scope are disabled, users excepted
+// since nothing can be synced without them. Any other failure fails the
+// validation, so a resource type is never dropped from the sync by mistake.
+func (d *Connector) probeResourceTypes(ctx context.Context, report *ValidationReport) {
+	l := ctxzap.Extract(ctx)
+
+	disabled := make(map[string]string)
+	for _, resourceType := range d.resourceTypes {
+		var target map[string]interface{}
+		response, _, err := d.client.Query(ctx, resourceTypeAccesses[resourceType].probe(d), &target)
+		if err != nil {
+			category := classifyError(err)
+			disable := category == ValidationCategoryScope && resourceType != userResourceType.Id
+			report.add(fmt.Sprintf("graphql %s", resourceType), category, err, !disable)
+			if disable {
+				l.Warn(
+					"baton-coupa: disabling resource type, check the OAuth client scopes",
+					zap.String("resource_type", resourceType),
+					zap.Strings("scopes", resourceTypeAccesses[resourceType].scopes),
+					zap.Error(err),
+				)
+				disabled[resourceType] = err.Error()
+				report.DisabledSyncers = append(report.DisabledSyncers, resourceType)
+			}
+			continue
+		}
+		response.Body.Close()
+	}
+	d.disabledSyncers.set(disabled)
+}
+
+// scopedSyncer returns nothing for a resource type that was disabled by
+// Validate, so a missing scope does not fail the whole sync.
+type scopedSyncer struct {
+	connectorbuilder.ResourceSyncer
+	disabled *disabledSyncers
+}
+
+// scopedProvisioner keeps the provisioning of the wrapped syncer visible to
+// the connector builder.
+type scopedProvisioner struct {
+	scopedSyncer
+	provisioner connectorbuilder.ResourceProvisionerV2
+}
+
+func newScopedSyncer(syncer connectorbuilder.ResourceSyncer, disabled *disabledSyncers) connectorbuilder.ResourceSyncer {
+	scoped := scopedSyncer{
+		ResourceSyncer: syncer,
+		disabled:       disabled,
+	}
+	if provisioner, ok := syncer.(connectorbuilder.ResourceProvisionerV2); ok {
+		return &scopedProvisioner{
+			scopedSyncer: scoped,
+			provisioner:  provisioner,
+		}
+	}
+	return &scoped
+}
+
+func (s *scopedSyncer) isDisabled(ctx context.Context) bool {
+	return s.disabled.disabled(s.ResourceType(ctx).Id)
+}
+
+func (s *scopedSyncer) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if s.isDisabled(ctx) {
+		return nil, "", nil, nil
+	}
+	return s.ResourceSyncer.List(ctx, parentResourceID, pToken)
+}
+
+func (s *scopedSyncer) Entitlements(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	if s.isDisabled(ctx) {
+		return nil, "", nil, nil
+	}
+	return s.ResourceSyncer.Entitlements(ctx, resource, pToken)
+}
+
+func (s *scopedSyncer) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	if s.isDisabled(ctx) {
+		return nil, "", nil, nil
+	}
+	return s.ResourceSyncer.Grants(ctx, resource, pToken)
+}
+
+func (s *scopedProvisioner) Grant(ctx context.Context, resource *v2.Resource, entitlement *v2.Entitlement) ([]*v2.Grant, annotations.Annotations, error) {
+	ctx = client.WithJournalTask(ctx, client.JournalTask{
+		Operation:    "grant",
+		ResourceType: entitlement.Resource.Id.ResourceType,
+		ResourceId:   entitlement.Resource.Id.Resource,
+		Entitlement:  entitlement.Id,
+		Principal:    resource.Id.Resource,
+	})
+	return s.provisioner.Grant(ctx, resource, entitlement)
+}
+
+func (s *scopedProvisioner) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	ctx = client.WithJournalTask(ctx, client.JournalTask{
+		Operation:    "revoke",
+		ResourceType: grant.Entitlement.Resource.Id.ResourceType,
+		ResourceId:   grant.Entitlement.Resource.Id.Resource,
+		Entitlement:  grant.Entitlement.Id,
+		Principal:    grant.Principal.Id.Resource,
+	})
+	return s.provisioner.Revoke(ctx, grant)
+}
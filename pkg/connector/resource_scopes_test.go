@@ -0,0 +1,59 @@
+package connector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequiredScopes(t *testing.T) {
+	testCases := []struct {
+		message             string
+		resourceTypes       []string
+		syncOffboardingRisk bool
+		expected            []string
+	}{
+		{
+			message:       "users only",
+			resourceTypes: []string{"user"},
+			expected:      []string{"core.common.read", "core.user.read", "email login", "openid", "profile"},
+		}, {
+			message:       "approvals",
+			resourceTypes: []string{"user", "approval_group", "approval_limit"},
+			expected:      []string{"core.approval.read", "core.common.read", "core.user.read", "email login", "openid", "profile"},
+		}, {
+			message:             "offboarding risk",
+			resourceTypes:       []string{"user"},
+			syncOffboardingRisk: true,
+			expected: []string{
+				"core.approval.read",
+				"core.common.read",
+				"core.contract.read",
+				"core.invoice.read",
+				"core.requisition.read",
+				"core.user.read",
+				"email login",
+				"openid",
+				"profile",
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.message, func(t *testing.T) {
+			require.Equal(t, testCase.expected, requiredScopes(testCase.resourceTypes, testCase.syncOffboardingRisk))
+		})
+	}
+}
+
+func TestValidateResourceTypes(t *testing.T) {
+	resourceTypes, err := validateResourceTypes(nil)
+	require.NoError(t, err)
+	require.Len(t, resourceTypes, len(resourceTypeAccesses))
+
+	resourceTypes, err = validateResourceTypes([]string{"group"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"group", "user"}, resourceTypes)
+
+	_, err = validateResourceTypes([]string{"content_group"})
+	require.Error(t, err)
+}
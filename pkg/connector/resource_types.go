@@ -27,3 +27,86 @@ var licenseResourceType = &v2.ResourceType{
 	Id:          "license",
 	DisplayName: "license",
 }
+
+var approvalGroupResourceType = &v2.ResourceType{
+	Id:          "approval_group",
+	DisplayName: "approval group",
+	Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_GROUP},
+}
+
+var approvalChainResourceType = &v2.ResourceType{
+	Id:          "approval_chain",
+	DisplayName: "approval chain",
+}
+
+var approvalLimitResourceType = &v2.ResourceType{
+	Id:          "approval_limit",
+	DisplayName: "approval limit",
+}
+
+// The business entity resource type is the parent of its users and groups,
+// and has its users as members, so access can be scoped by legal entity.
+var businessEntityResourceType = &v2.ResourceType{
+	Id:          "business_entity",
+	DisplayName: "business entity",
+}
+
+var supplierResourceType = &v2.ResourceType{
+	Id:          "supplier",
+	DisplayName: "supplier",
+}
+
+// The supplier user resource type is for external users of the Coupa
+// Supplier Portal. They are children of their supplier.
+var supplierUserResourceType = &v2.ResourceType{
+	Id:          "supplier_user",
+	DisplayName: "supplier user",
+	Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_USER},
+}
+
+// The OAuth client resource type is for the OIDC clients integrations use to
+// call the Coupa API. They are modeled as service accounts.
+var oauthClientResourceType = &v2.ResourceType{
+	Id:          "oauth_client",
+	DisplayName: "OAuth client",
+	Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_USER},
+}
+
+var scopeResourceType = &v2.ResourceType{
+	Id:          "scope",
+	DisplayName: "scope",
+}
+
+// The API key resource type is for legacy Coupa API keys, modeled as
+// service accounts.
+var apiKeyResourceType = &v2.ResourceType{
+	Id:          "api_key",
+	DisplayName: "API key",
+	Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_USER},
+}
+
+var apiPermissionResourceType = &v2.ResourceType{
+	Id:          "api_permission",
+	DisplayName: "API permission",
+}
+
+var pcardResourceType = &v2.ResourceType{
+	Id:          "pcard",
+	DisplayName: "p-card",
+}
+
+var warehouseResourceType = &v2.ResourceType{
+	Id:          "warehouse",
+	DisplayName: "warehouse",
+}
+
+var budgetLineResourceType = &v2.ResourceType{
+	Id:          "budget_line",
+	DisplayName: "budget line",
+}
+
+var departmentResourceType = &v2.ResourceType{
+	Id:          "department",
+	DisplayName: "department",
+	Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_GROUP},
+}
@@ -0,0 +1,281 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/conductorone/baton-coupa/pkg/connector/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+// rateLimitHeadroom is the number of remaining requests below which a role
+// migration waits for the rate limit window to reset.
+const rateLimitHeadroom = 5
+
+type RoleMigrationResult struct {
+	UserId int    `json:"user_id"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// RoleMigrationReport is the per-user result of a role migration. Checkpoint
+// is the id of the last user that was processed; passing it back resumes the
+// migration after that user.
+type RoleMigrationReport struct {
+	FromRoleId int                   `json:"from_role_id"`
+	ToRoleId   int                   `json:"to_role_id"`
+	Move       bool                  `json:"move"`
+	Checkpoint string                `json:"checkpoint,omitempty"`
+	Succeeded  int                   `json:"succeeded"`
+	Failed     int                   `json:"failed"`
+	Results    []RoleMigrationResult `json:"results"`
+}
+
+// MigrateRoleMembers copies every member of a role to another role, and
+// removes them from the original role when move is set.
+func (d *Connector) MigrateRoleMembers(
+	ctx context.Context,
+	fromRoleId int,
+	toRoleId int,
+	move bool,
+	checkpoint string,
+) (*RoleMigrationReport, error) {
+	ctx = client.WithJournalTask(ctx, client.JournalTask{
+		Operation:    "migrate_role_members",
+		ResourceType: roleResourceType.Id,
+		ResourceId:   strconv.Itoa(toRoleId),
+	})
+	return newRoleBuilder(ctx, d.client, d.guardrails, nil).migrateMembers(ctx, fromRoleId, toRoleId, move, checkpoint)
+}
+
+// migrateMembers processes the members of the role one page at a time. The
+// report is returned with the checkpoint reached so far when the migration is
+// interrupted.
+func (o *roleBuilder) migrateMembers(
+	ctx context.Context,
+	fromRoleId int,
+	toRoleId int,
+	move bool,
+	checkpoint string,
+) (*RoleMigrationReport, error) {
+	l := ctxzap.Extract(ctx)
+
+	if fromRoleId == toRoleId {
+		return nil, errors.New("baton-coupa: source and target roles must be different")
+	}
+
+	err := o.guardrails.roles.check(roleResourceType.Id, strconv.Itoa(toRoleId))
+	if err != nil {
+		return nil, err
+	}
+
+	toRole, err := o.getRole(ctx, toRoleId)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &RoleMigrationReport{
+		FromRoleId: fromRoleId,
+		ToRoleId:   toRoleId,
+		Move:       move,
+		Checkpoint: checkpoint,
+		Results:    make([]RoleMigrationResult, 0),
+	}
+
+	for {
+		var target client.RoleGrantsQueryResponse
+		response, ratelimitData, err := o.client.Query(
+			ctx,
+			client.RoleGrantQuery(strconv.Itoa(fromRoleId), report.Checkpoint),
+			&target,
+		)
+		if err != nil {
+			return report, err
+		}
+		response.Body.Close()
+
+		if len(target.Users) == 0 {
+			return report, nil
+		}
+
+		l.Debug(
+			"baton-coupa: migrating role members batch",
+			zap.Int("from_role_id", fromRoleId),
+			zap.Int("to_role_id", toRoleId),
+			zap.Int("batch_size", len(target.Users)),
+			zap.String("checkpoint", report.Checkpoint),
+		)
+
+		for _, user := range target.Users {
+			err := waitForRateLimit(ctx, ratelimitData)
+			if err != nil {
+				return report, err
+			}
+
+			ratelimitData, err = o.migrateMember(ctx, user.Id, fromRoleId, toRole, move)
+			if err != nil {
+				report.Failed++
+				report.Results = append(report.Results, RoleMigrationResult{
+					UserId: user.Id,
+					Status: "failed",
+					Error:  err.Error(),
+				})
+			} else {
+				report.Succeeded++
+				report.Results = append(report.Results, RoleMigrationResult{
+					UserId: user.Id,
+					Status: "migrated",
+				})
+			}
+			report.Checkpoint = strconv.Itoa(user.Id)
+		}
+	}
+}
+
+func (o *roleBuilder) migrateMember(ctx context.Context, userId int, fromRoleId int, toRole *client.Role, move bool) (*v2.RateLimitDescription, error) {
+	err := o.guardrails.checkUser(strconv.Itoa(userId))
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := o.getUserRoles(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	newRoles := make([]int, 0)
+	for _, role := range user.Roles {
+		if move && role.ID == fromRoleId {
+			continue
+		}
+		newRoles = append(newRoles, role.ID)
+	}
+	if !slices.Contains(newRoles, toRole.ID) {
+		err = o.checkMigrationSod(ctx, userId, fromRoleId, toRole, move)
+		if err != nil {
+			return nil, err
+		}
+		newRoles = append(newRoles, toRole.ID)
+	}
+
+	if move {
+		index := slices.IndexFunc(user.Roles, func(role client.Role) bool {
+			return role.ID == fromRoleId
+		})
+		if index >= 0 {
+			err = o.guardrails.checkCriticalRoles(ctx, o.client, userId, user.Roles[index:index+1])
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		// Removing a role needs the roles to be cleared first, see Revoke.
+		_, _, err = o.client.SetRoles(ctx, userId, make([]int, 0))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	userResponse, ratelimitData, err := o.client.SetRoles(ctx, userId, newRoles)
+	if err == nil && len(userResponse.Roles) != len(newRoles) {
+		err = fmt.Errorf("baton-coupa: roles not set for user %d", userId)
+	}
+	if err != nil {
+		if move {
+			// The roles were cleared, put the original ones back so the user
+			// is not left without any.
+			return ratelimitData, o.restoreRoles(ctx, userId, user.Roles, err)
+		}
+		return ratelimitData, err
+	}
+
+	return ratelimitData, nil
+}
+
+// checkMigrationSod checks the separation-of-duties rules a user would newly
+// violate once given the target role. When moving, the user no longer holds
+// the original role.
+func (o *roleBuilder) checkMigrationSod(ctx context.Context, userId int, fromRoleId int, toRole *client.Role, move bool) error {
+	if len(o.guardrails.sod.Rules) == 0 {
+		return nil
+	}
+
+	accesses, err := o.guardrails.sod.getUserSodAccesses(ctx, o.client, userId)
+	if err != nil {
+		return err
+	}
+	if move {
+		accesses = slices.DeleteFunc(accesses, func(access sodAccess) bool {
+			return access.ResourceType == roleResourceType.Id && access.Id == strconv.Itoa(fromRoleId)
+		})
+	}
+
+	return o.guardrails.checkSodAccess(ctx, userId, accesses, sodAccess{
+		ResourceType: roleResourceType.Id,
+		Id:           strconv.Itoa(toRole.ID),
+		Name:         toRole.Name,
+	})
+}
+
+// restoreRoles sets the roles a user had before a failed migration.
+func (o *roleBuilder) restoreRoles(ctx context.Context, userId int, roles []client.Role, cause error) error {
+	roleIds := make([]int, 0, len(roles))
+	for _, role := range roles {
+		roleIds = append(roleIds, role.ID)
+	}
+
+	userResponse, _, err := o.client.SetRoles(ctx, userId, roleIds)
+	if err == nil && len(userResponse.Roles) != len(roleIds) {
+		err = errors.New("roles not set")
+	}
+	if err != nil {
+		return fmt.Errorf("%w, and restoring the roles of user %d failed: %w", cause, userId, err)
+	}
+	return fmt.Errorf("%w, the roles of user %d were restored", cause, userId)
+}
+
+// getRole fetches a role by id.
+func (o *roleBuilder) getRole(ctx context.Context, roleId int) (*client.Role, error) {
+	var target client.RolesQueryResponse
+	response, _, err := o.client.Query(ctx, client.RoleQuery(roleId), &target)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if len(target.Roles) == 0 {
+		return nil, fmt.Errorf("baton-coupa: role %d not found", roleId)
+	}
+	return target.Roles[0], nil
+}
+
+// waitForRateLimit waits for the rate limit window to reset when few
+// requests remain in it.
+func waitForRateLimit(ctx context.Context, ratelimitData *v2.RateLimitDescription) error {
+	if ratelimitData == nil || ratelimitData.Limit == 0 || ratelimitData.ResetAt == nil {
+		return nil
+	}
+	if ratelimitData.Remaining > rateLimitHeadroom {
+		return nil
+	}
+
+	wait := time.Until(ratelimitData.ResetAt.AsTime())
+	if wait <= 0 {
+		return nil
+	}
+
+	ctxzap.Extract(ctx).Info("baton-coupa: waiting for rate limit reset", zap.Duration("wait", wait))
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(wait):
+		return nil
+	}
+}
@@ -21,26 +21,46 @@ import (
 const roleMemberEntitlementName = "member"
 
 type roleBuilder struct {
-	client *client.Client
+	client     *client.Client
+	guardrails *guardrails
+	// classifier is only set when syncing.
+	classifier *roleClassifier
 }
 
 func (o *roleBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
 	return roleResourceType
 }
 
-func roleResource(role *client.Role, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+// roleResource creates a role resource. classification, when set, is added to
+// the profile, and privileged roles are annotated.
+func roleResource(role *client.Role, classification *roleClassification, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
 	description := fmt.Sprintf("%s role in Coupa", role.Name)
 	if role.Description != nil && *role.Description != "" {
 		description = *role.Description
 	}
 
+	roleTraitOptions := []resourceSdk.RoleTraitOption{}
+	resourceOptions := []resourceSdk.ResourceOption{
+		resourceSdk.WithParentResourceID(parentResourceID),
+		resourceSdk.WithDescription(description),
+	}
+	if classification != nil {
+		roleTraitOptions = append(roleTraitOptions, resourceSdk.WithRoleProfile(classification.profile()))
+		if classification.Privileged {
+			risk, err := riskAnnotation(classification.Reasons)
+			if err != nil {
+				return nil, err
+			}
+			resourceOptions = append(resourceOptions, resourceSdk.WithAnnotation(risk))
+		}
+	}
+
 	return resourceSdk.NewRoleResource(
 		role.Name,
 		roleResourceType,
 		role.ID,
-		[]resourceSdk.RoleTraitOption{},
-		resourceSdk.WithParentResourceID(parentResourceID),
-		resourceSdk.WithDescription(description),
+		roleTraitOptions,
+		resourceOptions...,
 	)
 }
 
@@ -60,21 +80,21 @@ func (o *roleBuilder) List(
 	outputResources := make([]*v2.Resource, 0)
 	var outputAnnotations annotations.Annotations
 
-	var target client.RolesQueryResponse
-	response, ratelimitData, err := o.client.Query(
-		ctx,
-		client.RolesQuery(pToken.Token),
-		&target,
-	)
+	target, ratelimitData, err := o.listRoles(ctx, pToken.Token)
 	outputAnnotations.WithRateLimiting(ratelimitData)
 	if err != nil {
 		return nil, "", outputAnnotations, err
 	}
-	defer response.Body.Close()
 
 	lastId := ""
 	for _, role := range target.Roles {
-		resource, err := roleResource(role, parentResourceID)
+		var classification *roleClassification
+		if o.classifier != nil {
+			classified := o.classifier.classify(role)
+			classification = &classified
+		}
+
+		resource, err := roleResource(role, classification, parentResourceID)
 		if err != nil {
 			return nil, "", nil, err
 		}
@@ -85,6 +105,48 @@ func (o *roleBuilder) List(
 	return outputResources, lastId, outputAnnotations, nil
 }
 
+// listRoles lists a page of roles, with their permissions when the instance
+// returns them.
+func (o *roleBuilder) listRoles(ctx context.Context, pg string) (*client.RolesQueryResponse, *v2.RateLimitDescription, error) {
+	if o.classifier != nil && !o.classifier.permissionsUnavailable.Load() {
+		var target client.RolesQueryResponse
+		response, ratelimitData, err := o.client.Query(
+			ctx,
+			client.RolesWithPermissionsQuery(pg),
+			&target,
+		)
+		if err == nil {
+			response.Body.Close()
+			return &target, ratelimitData, nil
+		}
+
+		// Only a schema error means the permissions will never be returned,
+		// any other error fails the page so it is retried.
+		var graphqlError *client.GraphqlError
+		if !errors.As(err, &graphqlError) || !graphqlError.IsSchemaError() {
+			return nil, ratelimitData, err
+		}
+
+		ctxzap.Extract(ctx).Warn(
+			"baton-coupa: role permissions are not available, roles are only classified by configuration",
+			zap.Error(err),
+		)
+		o.classifier.permissionsUnavailable.Store(true)
+	}
+
+	var target client.RolesQueryResponse
+	response, ratelimitData, err := o.client.Query(
+		ctx,
+		client.RolesQuery(pg),
+		&target,
+	)
+	if err != nil {
+		return nil, ratelimitData, err
+	}
+	response.Body.Close()
+	return &target, ratelimitData, nil
+}
+
 func (o *roleBuilder) Entitlements(
 	_ context.Context,
 	resource *v2.Resource,
@@ -95,17 +157,28 @@ func (o *roleBuilder) Entitlements(
 	annotations.Annotations,
 	error,
 ) {
+	options := []entitlement.EntitlementOption{
+		entitlement.WithGrantableTo(userResourceType),
+		entitlement.WithDisplayName(
+			fmt.Sprintf("%s Role", resource.DisplayName),
+		),
+		entitlement.WithDescription(
+			fmt.Sprintf("%s role in Coupa", resource.DisplayName),
+		),
+	}
+	if reasons, ok := privilegedRoleReasons(resource); ok {
+		risk, err := riskAnnotation(reasons)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		options = append(options, entitlement.WithAnnotation(risk))
+	}
+
 	return []*v2.Entitlement{
 		entitlement.NewAssignmentEntitlement(
 			resource,
 			roleMemberEntitlementName,
-			entitlement.WithGrantableTo(userResourceType),
-			entitlement.WithDisplayName(
-				fmt.Sprintf("%s Role", resource.DisplayName),
-			),
-			entitlement.WithDescription(
-				fmt.Sprintf("%s role in Coupa", resource.DisplayName),
-			),
+			options...,
 		),
 	}, "", nil, nil
 }
@@ -163,6 +236,11 @@ func (o *roleBuilder) Grants(
 }
 
 func (o *roleBuilder) Grant(ctx context.Context, resource *v2.Resource, entitlement *v2.Entitlement) ([]*v2.Grant, annotations.Annotations, error) {
+	err := o.guardrails.checkGrant(entitlement.Resource.Id.ResourceType, entitlement.Resource.Id.Resource, resource.Id.Resource)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	roleIdToAdd, err := strconv.Atoi(entitlement.Resource.Id.Resource)
 	if err != nil {
 		return nil, nil, err
@@ -173,6 +251,11 @@ func (o *roleBuilder) Grant(ctx context.Context, resource *v2.Resource, entitlem
 		return nil, nil, err
 	}
 
+	err = o.guardrails.checkSod(ctx, o.client, userId, sodAccessOf(entitlement))
+	if err != nil {
+		return nil, nil, err
+	}
+
 	user, err := o.getUserRoles(ctx, userId)
 	if err != nil {
 		return nil, nil, err
@@ -214,6 +297,11 @@ func (o *roleBuilder) Grant(ctx context.Context, resource *v2.Resource, entitlem
 }
 
 func (o *roleBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	err := o.guardrails.checkUser(grant.Principal.Id.Resource)
+	if err != nil {
+		return nil, err
+	}
+
 	l := ctxzap.Extract(ctx)
 
 	if grant.Principal.Id.ResourceType != userResourceType.Id {
@@ -246,6 +334,11 @@ func (o *roleBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.
 		return annotations.New(&v2.GrantAlreadyRevoked{}), nil
 	}
 
+	err = o.guardrails.checkCriticalRoles(ctx, o.client, userId, user.Roles[index:index+1])
+	if err != nil {
+		return nil, err
+	}
+
 	if index == 0 {
 		user.Roles = user.Roles[1:]
 	} else {
@@ -305,8 +398,10 @@ func (o *roleBuilder) getUserRoles(ctx context.Context, userId int) (*client.Use
 	return &target.Users[0], nil
 }
 
-func newRoleBuilder(ctx context.Context, client *client.Client) *roleBuilder {
+func newRoleBuilder(ctx context.Context, client *client.Client, guardrails *guardrails, classifier *roleClassifier) *roleBuilder {
 	return &roleBuilder{
-		client: client,
+		client:     client,
+		guardrails: guardrails,
+		classifier: classifier,
 	}
 }
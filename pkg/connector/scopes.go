@@ -0,0 +1,144 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/conductorone/baton-coupa/pkg/connector/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	resourceSdk "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+)
+
+const scopeGrantedEntitlementName = "granted"
+
+// scopeBuilder syncs the OAuth scopes held by the OAuth clients of the
+// instance. Scopes are not listed by Coupa, they are collected from the
+// clients.
+type scopeBuilder struct {
+	client *client.Client
+}
+
+func (o *scopeBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return scopeResourceType
+}
+
+func scopeResource(scope string, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	return resourceSdk.NewResource(
+		scope,
+		scopeResourceType,
+		scope,
+		resourceSdk.WithParentResourceID(parentResourceID),
+		resourceSdk.WithDescription(fmt.Sprintf("%s OAuth scope in Coupa", scope)),
+	)
+}
+
+// List returns every scope granted to at least one OAuth client.
+func (o *scopeBuilder) List(
+	ctx context.Context,
+	parentResourceID *v2.ResourceId,
+	_ *pagination.Token,
+) (
+	[]*v2.Resource,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	logger := ctxzap.Extract(ctx)
+	logger.Debug("Starting Scopes List")
+
+	outputResources := make([]*v2.Resource, 0)
+	var outputAnnotations annotations.Annotations
+
+	scopes := make([]string, 0)
+	lastId := ""
+	for {
+		var target client.OAuthClientsQueryResponse
+		response, ratelimitData, err := o.client.Query(
+			ctx,
+			client.OAuthClientsQuery(lastId),
+			&target,
+		)
+		outputAnnotations.WithRateLimiting(ratelimitData)
+		if err != nil {
+			return nil, "", outputAnnotations, err
+		}
+		response.Body.Close()
+
+		if len(target.OAuthClients) == 0 {
+			break
+		}
+
+		for _, oauthClient := range target.OAuthClients {
+			for _, scope := range oauthClient.Scopes {
+				if !slices.Contains(scopes, scope) {
+					scopes = append(scopes, scope)
+				}
+			}
+			lastId = strconv.Itoa(oauthClient.ID)
+		}
+	}
+
+	slices.Sort(scopes)
+	for _, scope := range scopes {
+		resource, err := scopeResource(scope, parentResourceID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		outputResources = append(outputResources, resource)
+	}
+
+	return outputResources, "", outputAnnotations, nil
+}
+
+func (o *scopeBuilder) Entitlements(
+	_ context.Context,
+	resource *v2.Resource,
+	_ *pagination.Token,
+) (
+	[]*v2.Entitlement,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	return []*v2.Entitlement{
+		entitlement.NewPermissionEntitlement(
+			resource,
+			scopeGrantedEntitlementName,
+			entitlement.WithGrantableTo(oauthClientResourceType),
+			entitlement.WithDisplayName(
+				fmt.Sprintf("%s Scope", resource.DisplayName),
+			),
+			entitlement.WithDescription(
+				fmt.Sprintf("%s OAuth scope in Coupa", resource.DisplayName),
+			),
+			entitlement.WithAnnotation(&v2.EntitlementImmutable{}),
+		),
+	}, "", nil, nil
+}
+
+// Grants always returns an empty slice for scopes, the grants are returned by
+// the OAuth clients that hold them.
+func (o *scopeBuilder) Grants(
+	_ context.Context,
+	_ *v2.Resource,
+	_ *pagination.Token,
+) (
+	[]*v2.Grant,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	return nil, "", nil, nil
+}
+
+func newScopeBuilder(ctx context.Context, client *client.Client) *scopeBuilder {
+	return &scopeBuilder{
+		client: client,
+	}
+}
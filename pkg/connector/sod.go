@@ -0,0 +1,340 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+
+	"github.com/conductorone/baton-coupa/pkg/connector/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"gopkg.in/yaml.v3"
+)
+
+type sodAction string
+
+const (
+	sodActionBlock sodAction = "block"
+	sodActionWarn  sodAction = "warn"
+)
+
+// sodAccess is an access a separation-of-duties rule refers to. Roles, groups
+// and licenses can be matched by id or name, approval limits by id.
+type sodAccess struct {
+	ResourceType string `yaml:"resource_type"`
+	Id           string `yaml:"id"`
+	Name         string `yaml:"name"`
+}
+
+// sodAccessOf returns the access given by an entitlement.
+func sodAccessOf(entitlement *v2.Entitlement) sodAccess {
+	return sodAccess{
+		ResourceType: entitlement.Resource.Id.ResourceType,
+		Id:           entitlement.Resource.Id.Resource,
+		Name:         entitlement.Resource.DisplayName,
+	}
+}
+
+func (a sodAccess) matches(access sodAccess) bool {
+	if a.ResourceType != access.ResourceType {
+		return false
+	}
+	return (a.Id != "" && a.Id == access.Id) || (a.Name != "" && a.Name == access.Name)
+}
+
+// sodRule is violated by a user who holds every access in Conflicts.
+type sodRule struct {
+	Name        string      `yaml:"name"`
+	Description string      `yaml:"description"`
+	Action      sodAction   `yaml:"action"`
+	Conflicts   []sodAccess `yaml:"conflicts"`
+}
+
+func (r sodRule) violatedBy(accesses []sodAccess) bool {
+	for _, conflict := range r.Conflicts {
+		if !slices.ContainsFunc(accesses, conflict.matches) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r sodRule) involves(access sodAccess) bool {
+	return slices.ContainsFunc(r.Conflicts, func(conflict sodAccess) bool {
+		return conflict.matches(access)
+	})
+}
+
+type sodRules struct {
+	Rules []sodRule `yaml:"rules"`
+}
+
+var sodResourceTypes = []string{
+	roleResourceType.Id,
+	groupResourceType.Id,
+	licenseResourceType.Id,
+	approvalLimitResourceType.Id,
+}
+
+// loadSodRules reads the separation-of-duties rules from a YAML file, for
+// example:
+//
+//	rules:
+//	  - name: supplier-manager-invoice-approver
+//	    action: block
+//	    conflicts:
+//	      - resource_type: role
+//	        name: Supplier Manager
+//	      - resource_type: role
+//	        name: AP Invoice Approver
+func loadSodRules(path string) (*sodRules, error) {
+	rules := &sodRules{}
+	if path == "" {
+		return rules, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("baton-coupa: reading separation of duties rules: %w", err)
+	}
+
+	err = yaml.Unmarshal(data, rules)
+	if err != nil {
+		return nil, fmt.Errorf("baton-coupa: parsing separation of duties rules: %w", err)
+	}
+
+	for _, rule := range rules.Rules {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("baton-coupa: separation of duties rule without a name")
+		}
+		if rule.Action != sodActionBlock && rule.Action != sodActionWarn {
+			return nil, fmt.Errorf("baton-coupa: separation of duties rule %s has an unknown action %s", rule.Name, rule.Action)
+		}
+		if len(rule.Conflicts) < 2 {
+			return nil, fmt.Errorf("baton-coupa: separation of duties rule %s needs at least two conflicting accesses", rule.Name)
+		}
+		for _, conflict := range rule.Conflicts {
+			if !slices.Contains(sodResourceTypes, conflict.ResourceType) {
+				return nil, fmt.Errorf("baton-coupa: separation of duties rule %s has an unknown resource type %s", rule.Name, conflict.ResourceType)
+			}
+		}
+	}
+
+	return rules, nil
+}
+
+// resourceTypes returns the resource types the rules refer to, so only those
+// are fetched for a user.
+func (s *sodRules) resourceTypes() []string {
+	rv := make([]string, 0)
+	for _, rule := range s.Rules {
+		for _, conflict := range rule.Conflicts {
+			if !slices.Contains(rv, conflict.ResourceType) {
+				rv = append(rv, conflict.ResourceType)
+			}
+		}
+	}
+	return rv
+}
+
+// violations returns the rules violated by the given accesses.
+func (s *sodRules) violations(accesses []sodAccess) []sodRule {
+	rv := make([]sodRule, 0)
+	for _, rule := range s.Rules {
+		if rule.violatedBy(accesses) {
+			rv = append(rv, rule)
+		}
+	}
+	return rv
+}
+
+// sodUser is a user queried with the fields holding the accesses the rules
+// refer to. License flags are read from the raw fields.
+type sodUser struct {
+	Id                       int                `json:"id"`
+	Roles                    []client.Role      `json:"roles"`
+	Groups                   []client.Group     `json:"userGroups"`
+	RequisitionApprovalLimit *client.ResourceId `json:"requisitionApprovalLimit"`
+	ExpenseApprovalLimit     *client.ResourceId `json:"expenseApprovalLimit"`
+}
+
+// userFields returns the GraphQL user fields holding the accesses the rules
+// refer to.
+func (s *sodRules) userFields() []string {
+	fields := make([]string, 0)
+	for _, resourceType := range s.resourceTypes() {
+		switch resourceType {
+		case roleResourceType.Id:
+			fields = append(fields, "roles { id name }")
+		case groupResourceType.Id:
+			fields = append(fields, "userGroups { id name }")
+		case licenseResourceType.Id:
+			for _, license := range coupaLicenses {
+				fields = append(fields, licenseQueryField(license.ID))
+			}
+		case approvalLimitResourceType.Id:
+			fields = append(fields, "requisitionApprovalLimit { id }", "expenseApprovalLimit { id }")
+		}
+	}
+	return fields
+}
+
+// userSodAccesses reads the accesses the rules refer to from a user queried
+// with userFields, and returns the id of the user with them.
+func (s *sodRules) userSodAccesses(data json.RawMessage) (int, []sodAccess, error) {
+	var user sodUser
+	err := json.Unmarshal(data, &user)
+	if err != nil {
+		return 0, nil, err
+	}
+	var flags map[string]interface{}
+	err = json.Unmarshal(data, &flags)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	accesses := make([]sodAccess, 0)
+	for _, resourceType := range s.resourceTypes() {
+		switch resourceType {
+		case roleResourceType.Id:
+			for _, role := range user.Roles {
+				accesses = append(accesses, sodAccess{ResourceType: resourceType, Id: strconv.Itoa(role.ID), Name: role.Name})
+			}
+		case groupResourceType.Id:
+			for _, group := range user.Groups {
+				accesses = append(accesses, sodAccess{ResourceType: resourceType, Id: strconv.Itoa(group.ID), Name: group.Name})
+			}
+		case licenseResourceType.Id:
+			for _, license := range coupaLicenses {
+				if assigned, ok := flags[licenseQueryField(license.ID)].(bool); ok && assigned {
+					accesses = append(accesses, sodAccess{ResourceType: resourceType, Id: license.ID, Name: license.Name})
+				}
+			}
+		case approvalLimitResourceType.Id:
+			for _, limit := range []*client.ResourceId{user.RequisitionApprovalLimit, user.ExpenseApprovalLimit} {
+				if limit != nil {
+					accesses = append(accesses, sodAccess{ResourceType: resourceType, Id: strconv.Itoa(limit.Id)})
+				}
+			}
+		}
+	}
+	return user.Id, accesses, nil
+}
+
+// getUsersSodAccesses fetches the accesses the rules refer to of several
+// users in a single query.
+func (s *sodRules) getUsersSodAccesses(ctx context.Context, coupaClient *client.Client, userIds []int) (map[int][]sodAccess, error) {
+	rv := make(map[int][]sodAccess, len(userIds))
+	if len(s.Rules) == 0 {
+		for _, userId := range userIds {
+			rv[userId] = make([]sodAccess, 0)
+		}
+		return rv, nil
+	}
+	if len(userIds) == 0 {
+		return rv, nil
+	}
+
+	var target client.UsersFieldsResponse
+	response, _, err := coupaClient.Query(
+		ctx,
+		client.UsersFieldsQuery(userIds, s.userFields()),
+		&target,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	for _, data := range target.Users {
+		userId, accesses, err := s.userSodAccesses(data)
+		if err != nil {
+			return nil, err
+		}
+		rv[userId] = accesses
+	}
+	return rv, nil
+}
+
+// getUserSodAccesses fetches the accesses of a user the rules refer to.
+func (s *sodRules) getUserSodAccesses(ctx context.Context, coupaClient *client.Client, userId int) ([]sodAccess, error) {
+	usersAccesses, err := s.getUsersSodAccesses(ctx, coupaClient, []int{userId})
+	if err != nil {
+		return nil, err
+	}
+	accesses, ok := usersAccesses[userId]
+	if !ok {
+		return nil, fmt.Errorf("baton-coupa: user %d not found", userId)
+	}
+	return accesses, nil
+}
+
+// checkSod checks the rules a user would newly violate once granted the access.
+// Blocking rules deny the grant; warning rules are only logged.
+func (g *guardrails) checkSod(ctx context.Context, coupaClient *client.Client, userId int, access sodAccess) error {
+	if len(g.sod.Rules) == 0 {
+		return nil
+	}
+
+	accesses, err := g.sod.getUserSodAccesses(ctx, coupaClient, userId)
+	if err != nil {
+		return err
+	}
+	return g.checkSodAccess(ctx, userId, accesses, access)
+}
+
+// checkSodAccess checks the rules a user holding accesses would newly violate
+// once granted the access.
+func (g *guardrails) checkSodAccess(ctx context.Context, userId int, accesses []sodAccess, access sodAccess) error {
+	granted := append(slices.Clone(accesses), access)
+
+	for _, rule := range g.sod.violations(granted) {
+		// Rules the user already violates are reported by the sync.
+		if !rule.involves(access) || rule.violatedBy(accesses) {
+			continue
+		}
+
+		if rule.Action == sodActionBlock {
+			return status.Errorf(
+				codes.PermissionDenied,
+				"baton-coupa: granting %s %s to user %d violates separation of duties rule %s",
+				access.ResourceType,
+				access.Id,
+				userId,
+				rule.Name,
+			)
+		}
+
+		ctxzap.Extract(ctx).Warn(
+			"baton-coupa: grant violates separation of duties rule",
+			zap.String("rule", rule.Name),
+			zap.String("resource_type", access.ResourceType),
+			zap.String("resource_id", access.Id),
+			zap.Int("user_id", userId),
+		)
+	}
+	return nil
+}
+
+// sodViolationsAnnotation lists the separation-of-duties rules a user
+// violates.
+func sodViolationsAnnotation(violations []sodRule) (*structpb.Struct, error) {
+	rules := make([]interface{}, 0, len(violations))
+	for _, rule := range violations {
+		rules = append(rules, map[string]interface{}{
+			"name":        rule.Name,
+			"description": rule.Description,
+			"action":      string(rule.Action),
+		})
+	}
+	return structpb.NewStruct(map[string]interface{}{
+		"sod_violations": rules,
+	})
+}
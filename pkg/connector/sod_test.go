@@ -0,0 +1,85 @@
+package connector
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testSodRules = `rules:
+  - name: supplier-manager-invoice-approver
+    action: block
+    conflicts:
+      - resource_type: role
+        name: Supplier Manager
+      - resource_type: role
+        name: AP Invoice Approver
+  - name: expense-limit-expense-user
+    action: warn
+    conflicts:
+      - resource_type: approval_limit
+        id: "7"
+      - resource_type: license
+        id: expense-user
+`
+
+func TestSodRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sod.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(testSodRules), 0o600))
+
+	rules, err := loadSodRules(path)
+	require.NoError(t, err)
+	require.Len(t, rules.Rules, 2)
+	require.ElementsMatch(t, []string{"role", "approval_limit", "license"}, rules.resourceTypes())
+
+	accesses := []sodAccess{
+		{ResourceType: roleResourceType.Id, Id: "1", Name: "Supplier Manager"},
+		{ResourceType: licenseResourceType.Id, Id: "expense-user", Name: "Expense"},
+	}
+	require.Empty(t, rules.violations(accesses))
+
+	accesses = append(accesses, sodAccess{ResourceType: roleResourceType.Id, Id: "2", Name: "AP Invoice Approver"})
+	violations := rules.violations(accesses)
+	require.Len(t, violations, 1)
+	require.Equal(t, "supplier-manager-invoice-approver", violations[0].Name)
+
+	accesses = append(accesses, sodAccess{ResourceType: approvalLimitResourceType.Id, Id: "7"})
+	require.Len(t, rules.violations(accesses), 2)
+}
+
+func TestLoadSodRulesInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sod.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("rules:\n  - name: one\n    action: deny\n"), 0o600))
+
+	_, err := loadSodRules(path)
+	require.Error(t, err)
+}
+
+func TestUserSodAccesses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sod.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(testSodRules), 0o600))
+	rules, err := loadSodRules(path)
+	require.NoError(t, err)
+
+	require.Contains(t, rules.userFields(), "roles { id name }")
+	require.NotContains(t, rules.userFields(), "userGroups { id name }")
+
+	userId, accesses, err := rules.userSodAccesses(json.RawMessage(`{
+		"id": 7,
+		"roles": [{"id": 2, "name": "AP Invoice Approver"}],
+		"requisitionApprovalLimit": null,
+		"expenseApprovalLimit": {"id": 7},
+		"` + licenseQueryField("expense-user") + `": true
+	}`))
+	require.NoError(t, err)
+	require.Equal(t, 7, userId)
+	require.ElementsMatch(t, []sodAccess{
+		{ResourceType: roleResourceType.Id, Id: "2", Name: "AP Invoice Approver"},
+		{ResourceType: approvalLimitResourceType.Id, Id: "7"},
+		{ResourceType: licenseResourceType.Id, Id: "expense-user", Name: "Expense"},
+	}, accesses)
+	require.Len(t, rules.violations(accesses), 1)
+}
@@ -0,0 +1,156 @@
+package connector
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/conductorone/baton-coupa/pkg/connector/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	resourceSdk "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+type supplierUserBuilder struct {
+	client *client.Client
+}
+
+func (o *supplierUserBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return supplierUserResourceType
+}
+
+// Create a new connector resource for a Coupa Supplier Portal user.
+func supplierUserResource(supplierUser *client.SupplierUser, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	status := v2.UserTrait_Status_STATUS_DISABLED
+	if supplierUser.Active {
+		status = v2.UserTrait_Status_STATUS_ENABLED
+	}
+
+	fullName := strings.TrimSpace(strings.Join([]string{supplierUser.FirstName, supplierUser.LastName}, " "))
+	if fullName == "" {
+		fullName = supplierUser.Email
+	}
+
+	profile := map[string]interface{}{
+		"id":         supplierUser.ID,
+		"email":      supplierUser.Email,
+		"first_name": supplierUser.FirstName,
+		"last_name":  supplierUser.LastName,
+		"active":     supplierUser.Active,
+	}
+	if parentResourceID != nil {
+		profile["supplier_id"] = parentResourceID.Resource
+	}
+
+	options := []resourceSdk.UserTraitOption{
+		resourceSdk.WithEmail(supplierUser.Email, true),
+		resourceSdk.WithStatus(status),
+		resourceSdk.WithUserProfile(profile),
+		resourceSdk.WithUserLogin(supplierUser.Email),
+		resourceSdk.WithAccountType(v2.UserTrait_ACCOUNT_TYPE_HUMAN),
+	}
+
+	if supplierUser.LastLoginAt != nil {
+		lastLogin, err := time.Parse(time.RFC3339, *supplierUser.LastLoginAt)
+		if err == nil {
+			options = append(options, resourceSdk.WithLastLogin(lastLogin))
+		}
+	}
+
+	return resourceSdk.NewUserResource(
+		fullName,
+		supplierUserResourceType,
+		supplierUser.ID,
+		options,
+		resourceSdk.WithParentResourceID(parentResourceID),
+	)
+}
+
+// List returns the supplier portal users of a supplier. Supplier users are
+// only listed under their supplier.
+func (o *supplierUserBuilder) List(
+	ctx context.Context,
+	parentResourceID *v2.ResourceId,
+	pToken *pagination.Token,
+) (
+	[]*v2.Resource,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	if parentResourceID == nil || parentResourceID.ResourceType != supplierResourceType.Id {
+		return nil, "", nil, nil
+	}
+
+	logger := ctxzap.Extract(ctx)
+	logger.Debug(
+		"Starting Supplier Users List",
+		zap.String("supplier_id", parentResourceID.Resource),
+		zap.String("token", pToken.Token),
+	)
+
+	outputResources := make([]*v2.Resource, 0)
+	var outputAnnotations annotations.Annotations
+
+	var target client.SupplierUsersQueryResponse
+	response, ratelimitData, err := o.client.Query(
+		ctx,
+		client.SupplierUsersQuery(parentResourceID.Resource, pToken.Token),
+		&target,
+	)
+	outputAnnotations.WithRateLimiting(ratelimitData)
+	if err != nil {
+		return nil, "", outputAnnotations, err
+	}
+	defer response.Body.Close()
+
+	lastId := ""
+	for _, supplierUser := range target.SupplierUsers {
+		resource, err := supplierUserResource(supplierUser, parentResourceID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		outputResources = append(outputResources, resource)
+		lastId = strconv.Itoa(supplierUser.ID)
+	}
+
+	return outputResources, lastId, outputAnnotations, nil
+}
+
+// Entitlements always returns an empty slice for supplier users.
+func (o *supplierUserBuilder) Entitlements(
+	_ context.Context,
+	_ *v2.Resource,
+	_ *pagination.Token,
+) (
+	[]*v2.Entitlement,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	return nil, "", nil, nil
+}
+
+// Grants always returns an empty slice for supplier users since they don't have any entitlements.
+func (o *supplierUserBuilder) Grants(
+	_ context.Context,
+	_ *v2.Resource,
+	_ *pagination.Token,
+) (
+	[]*v2.Grant,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	return nil, "", nil, nil
+}
+
+func newSupplierUserBuilder(ctx context.Context, client *client.Client) *supplierUserBuilder {
+	return &supplierUserBuilder{
+		client: client,
+	}
+}
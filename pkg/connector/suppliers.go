@@ -0,0 +1,176 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/conductorone/baton-coupa/pkg/connector/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	resourceSdk "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+const supplierContactEntitlementName = "contact"
+
+type supplierBuilder struct {
+	client *client.Client
+}
+
+func (o *supplierBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return supplierResourceType
+}
+
+func supplierResource(supplier *client.Supplier, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	name := supplier.DisplayName
+	if name == "" {
+		name = supplier.Name
+	}
+
+	return resourceSdk.NewResource(
+		name,
+		supplierResourceType,
+		supplier.ID,
+		resourceSdk.WithParentResourceID(parentResourceID),
+		resourceSdk.WithDescription(fmt.Sprintf("%s supplier in Coupa (%s)", name, supplier.Status)),
+		resourceSdk.WithAnnotation(
+			&v2.ChildResourceType{ResourceTypeId: supplierUserResourceType.Id},
+		),
+	)
+}
+
+func (o *supplierBuilder) List(
+	ctx context.Context,
+	parentResourceID *v2.ResourceId,
+	pToken *pagination.Token,
+) (
+	[]*v2.Resource,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	logger := ctxzap.Extract(ctx)
+	logger.Debug("Starting Suppliers List", zap.String("token", pToken.Token))
+
+	outputResources := make([]*v2.Resource, 0)
+	var outputAnnotations annotations.Annotations
+
+	var target client.SuppliersQueryResponse
+	response, ratelimitData, err := o.client.Query(
+		ctx,
+		client.SuppliersQuery(pToken.Token),
+		&target,
+	)
+	outputAnnotations.WithRateLimiting(ratelimitData)
+	if err != nil {
+		return nil, "", outputAnnotations, err
+	}
+	defer response.Body.Close()
+
+	lastId := ""
+	for _, supplier := range target.Suppliers {
+		resource, err := supplierResource(supplier, parentResourceID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		outputResources = append(outputResources, resource)
+		lastId = strconv.Itoa(supplier.ID)
+	}
+
+	return outputResources, lastId, outputAnnotations, nil
+}
+
+func (o *supplierBuilder) Entitlements(
+	_ context.Context,
+	resource *v2.Resource,
+	_ *pagination.Token,
+) (
+	[]*v2.Entitlement,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	return []*v2.Entitlement{
+		entitlement.NewAssignmentEntitlement(
+			resource,
+			supplierContactEntitlementName,
+			entitlement.WithGrantableTo(supplierUserResourceType),
+			entitlement.WithDisplayName(
+				fmt.Sprintf("%s Supplier Contact", resource.DisplayName),
+			),
+			entitlement.WithDescription(
+				fmt.Sprintf("Supplier portal contact of %s in Coupa", resource.DisplayName),
+			),
+			entitlement.WithAnnotation(&v2.EntitlementImmutable{}),
+		),
+	}, "", nil, nil
+}
+
+// Grants returns a read-only contact grant for each supplier portal user of
+// the supplier.
+func (o *supplierBuilder) Grants(
+	ctx context.Context,
+	resource *v2.Resource,
+	pToken *pagination.Token,
+) (
+	[]*v2.Grant,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	logger := ctxzap.Extract(ctx)
+
+	supplierId := resource.Id.Resource
+
+	logger.Debug(
+		"Starting Suppliers Grants",
+		zap.String("supplier_id", supplierId),
+		zap.String("token", pToken.Token),
+	)
+
+	outputGrants := make([]*v2.Grant, 0)
+	var outputAnnotations annotations.Annotations
+
+	var target client.SupplierUsersQueryResponse
+	response, ratelimitData, err := o.client.Query(
+		ctx,
+		client.SupplierUsersQuery(supplierId, pToken.Token),
+		&target,
+	)
+	outputAnnotations.WithRateLimiting(ratelimitData)
+	if err != nil {
+		return nil, "", outputAnnotations, err
+	}
+	defer response.Body.Close()
+
+	lastId := ""
+	for _, supplierUser := range target.SupplierUsers {
+		supplierUserId := strconv.Itoa(supplierUser.ID)
+		outputGrants = append(
+			outputGrants,
+			grant.NewGrant(
+				resource,
+				supplierContactEntitlementName,
+				&v2.ResourceId{
+					ResourceType: supplierUserResourceType.Id,
+					Resource:     supplierUserId,
+				},
+				grant.WithAnnotation(&v2.GrantImmutable{}),
+			),
+		)
+		lastId = supplierUserId
+	}
+
+	return outputGrants, lastId, outputAnnotations, nil
+}
+
+func newSupplierBuilder(ctx context.Context, client *client.Client) *supplierBuilder {
+	return &supplierBuilder{
+		client: client,
+	}
+}
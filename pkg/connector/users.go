@@ -2,32 +2,61 @@ package connector
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/conductorone/baton-coupa/pkg/connector/client"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
 	resourceSdk "github.com/conductorone/baton-sdk/pkg/types/resource"
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
 	"go.uber.org/zap"
 )
 
+const userCanActAsEntitlementName = "can_act_as"
+
 type userBuilder struct {
-	client *client.Client
+	client     *client.Client
+	guardrails *guardrails
+	// documentCounter is only set when offboarding risk is synced.
+	documentCounter *documentCounter
+	// scopedByBusinessEntity is set when business entities are synced, so
+	// users are listed under their business entity.
+	scopedByBusinessEntity bool
 }
 
 func (o *userBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
 	return userResourceType
 }
 
-// Create a new connector resource for a Coupa user.
-func userResource(user *client.User, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+// Create a new connector resource for a Coupa user. documentCounts, when
+// set, adds the number of in-flight documents assigned to the user to the
+// profile.
+func userResource(
+	user *client.User,
+	documentCounts map[client.DocumentType]int,
+	parentResourceID *v2.ResourceId,
+) (*v2.Resource, error) {
 	status := v2.UserTrait_Status_STATUS_DISABLED
 	if user.Active {
 		status = v2.UserTrait_Status_STATUS_ENABLED
 	}
 
+	profile := map[string]interface{}{
+		"id":        user.ID,
+		"email":     user.Email,
+		"full_name": user.Fullname,
+		"active":    user.Active,
+	}
+	for documentType, count := range documentCounts {
+		profile[string(documentType)] = count
+	}
+
 	return resourceSdk.NewUserResource(
 		user.Fullname,
 		userResourceType,
@@ -35,13 +64,7 @@ func userResource(user *client.User, parentResourceID *v2.ResourceId) (*v2.Resou
 		[]resourceSdk.UserTraitOption{
 			resourceSdk.WithEmail(user.Email, true),
 			resourceSdk.WithStatus(status),
-			resourceSdk.WithUserProfile(
-				map[string]interface{}{
-					"id":        user.ID,
-					"email":     user.Email,
-					"full_name": user.Fullname,
-					"active":    user.Active,
-				}),
+			resourceSdk.WithUserProfile(profile),
 			resourceSdk.WithUserLogin(user.Email),
 		},
 		resourceSdk.WithParentResourceID(parentResourceID),
@@ -50,6 +73,8 @@ func userResource(user *client.User, parentResourceID *v2.ResourceId) (*v2.Resou
 
 // List returns all the users from the database as resource objects.
 // Users include a UserTrait because they are the 'shape' of a standard user.
+// When business entities are synced, only the users listed under the parent
+// resource are returned.
 func (o *userBuilder) List(
 	ctx context.Context,
 	parentResourceID *v2.ResourceId,
@@ -63,13 +88,24 @@ func (o *userBuilder) List(
 	logger := ctxzap.Extract(ctx)
 	logger.Debug("Starting Users List", zap.String("token", pToken.Token))
 
+	// The connector outlives a sync in service mode, so the document counts
+	// are loaded again when a sync starts listing users.
+	if o.documentCounter != nil && pToken.Token == "" {
+		o.documentCounter.reset()
+	}
+
 	outputResources := make([]*v2.Resource, 0)
 	var outputAnnotations annotations.Annotations
 
+	query := client.AllUsersQuery(pToken.Token)
+	if parentResourceID != nil && parentResourceID.ResourceType == businessEntityResourceType.Id {
+		query = client.BusinessEntityUsersQuery(parentResourceID.Resource, pToken.Token)
+	}
+
 	var target client.UsersQueryResponse
 	response, rateLimitData, err := o.client.Query(
 		ctx,
-		client.AllUsersQuery(pToken.Token),
+		query,
 		&target,
 	)
 	outputAnnotations.WithRateLimiting(rateLimitData)
@@ -80,23 +116,82 @@ func (o *userBuilder) List(
 
 	logger.Debug("Users List Response", zap.Any("response", target))
 
-	lastId := ""
+	users := make([]*client.User, 0, len(target.Users))
 	for _, user := range target.Users {
-		resource, err := userResource(user, parentResourceID)
+		if !o.scopedByBusinessEntity || listedUnder(user.BusinessEntities, parentResourceID) {
+			users = append(users, user)
+		}
+	}
+
+	// The accesses of the users of the page are fetched at once, to annotate
+	// the separation-of-duties rules they violate.
+	var sodAccesses map[int][]sodAccess
+	if len(o.guardrails.sod.Rules) > 0 {
+		userIds := make([]int, 0, len(users))
+		for _, user := range users {
+			userIds = append(userIds, user.ID)
+		}
+		sodAccesses, err = o.guardrails.sod.getUsersSodAccesses(ctx, o.client, userIds)
+		if err != nil {
+			return nil, "", outputAnnotations, err
+		}
+	}
+
+	for _, user := range users {
+		var documentCounts map[client.DocumentType]int
+		if o.documentCounter != nil {
+			documentCounts, err = o.documentCounter.Counts(ctx, user.ID)
+			if err != nil {
+				return nil, "", outputAnnotations, err
+			}
+		}
+
+		resource, err := userResource(user, documentCounts, parentResourceID)
 		if err != nil {
 			return nil, "", nil, err
 		}
+
+		if sodAccesses != nil {
+			err = o.annotateSodViolations(resource, sodAccesses[user.ID])
+			if err != nil {
+				return nil, "", outputAnnotations, err
+			}
+		}
 		outputResources = append(outputResources, resource)
-		lastId = strconv.Itoa(user.ID)
+	}
+
+	lastId := ""
+	if len(target.Users) > 0 {
+		lastId = strconv.Itoa(target.Users[len(target.Users)-1].ID)
 	}
 
 	return outputResources, lastId, outputAnnotations, nil
 }
 
-// Entitlements always returns an empty slice for users.
+// annotateSodViolations adds the separation-of-duties rules the accesses of
+// the user violate to the user resource.
+func (o *userBuilder) annotateSodViolations(resource *v2.Resource, accesses []sodAccess) error {
+	violations := o.guardrails.sod.violations(accesses)
+	if len(violations) == 0 {
+		return nil
+	}
+
+	violationsAnnotation, err := sodViolationsAnnotation(violations)
+	if err != nil {
+		return err
+	}
+
+	annos := annotations.Annotations(resource.Annotations)
+	annos.Append(violationsAnnotation)
+	resource.Annotations = annos
+	return nil
+}
+
+// Entitlements returns the can_act_as entitlement, which is granted to the
+// delegates of a user.
 func (o *userBuilder) Entitlements(
 	_ context.Context,
-	_ *v2.Resource,
+	resource *v2.Resource,
 	_ *pagination.Token,
 ) (
 	[]*v2.Entitlement,
@@ -104,25 +199,203 @@ func (o *userBuilder) Entitlements(
 	annotations.Annotations,
 	error,
 ) {
-	return nil, "", nil, nil
+	return []*v2.Entitlement{
+		entitlement.NewPermissionEntitlement(
+			resource,
+			userCanActAsEntitlementName,
+			entitlement.WithGrantableTo(userResourceType),
+			entitlement.WithDisplayName(
+				fmt.Sprintf("Can act as %s", resource.DisplayName),
+			),
+			entitlement.WithDescription(
+				fmt.Sprintf("Delegated approval authority of %s in Coupa", resource.DisplayName),
+			),
+		),
+	}, "", nil, nil
 }
 
-// Grants always returns an empty slice for users since they don't have any entitlements.
+// Grants returns a can_act_as grant for each delegate of the active or future
+// delegations created by the user. Every delegation is fetched at once, so
+// the delegations to a same delegate end up in one grant.
 func (o *userBuilder) Grants(
+	ctx context.Context,
+	resource *v2.Resource,
+	pToken *pagination.Token,
+) (
+	[]*v2.Grant,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	logger := ctxzap.Extract(ctx)
+
+	userId := resource.Id.Resource
+
+	logger.Debug(
+		"Starting Delegations Grants",
+		zap.String("user_id", userId),
+	)
+
+	var outputAnnotations annotations.Annotations
+
+	now := time.Now()
+	delegations := make([]*client.Delegation, 0)
+	lastId := ""
+	for {
+		var target client.DelegationsQueryResponse
+		response, ratelimitData, err := o.client.Query(
+			ctx,
+			client.DelegationsQuery(userId, now, lastId),
+			&target,
+		)
+		outputAnnotations.WithRateLimiting(ratelimitData)
+		if err != nil {
+			return nil, "", outputAnnotations, err
+		}
+		response.Body.Close()
+
+		if len(target.Delegations) == 0 {
+			break
+		}
+		delegations = append(delegations, target.Delegations...)
+		lastId = strconv.Itoa(target.Delegations[len(target.Delegations)-1].ID)
+	}
+
+	return delegationGrants(resource, delegations), "", outputAnnotations, nil
+}
+
+// delegationGrants returns one can_act_as grant per delegate, with the id and
+// date range of each delegation to them in its metadata.
+func delegationGrants(resource *v2.Resource, delegations []*client.Delegation) []*v2.Grant {
+	delegates := make([]int, 0)
+	byDelegate := make(map[int][]interface{})
+	for _, delegation := range delegations {
+		if _, ok := byDelegate[delegation.Delegate.Id]; !ok {
+			delegates = append(delegates, delegation.Delegate.Id)
+		}
+		byDelegate[delegation.Delegate.Id] = append(byDelegate[delegation.Delegate.Id], map[string]interface{}{
+			"delegation_id": delegation.ID,
+			"start_date":    delegation.StartDate,
+			"end_date":      delegation.EndDate,
+		})
+	}
+
+	outputGrants := make([]*v2.Grant, 0, len(delegates))
+	for _, delegateId := range delegates {
+		outputGrants = append(
+			outputGrants,
+			grant.NewGrant(
+				resource,
+				userCanActAsEntitlementName,
+				&v2.ResourceId{
+					ResourceType: userResourceType.Id,
+					Resource:     strconv.Itoa(delegateId),
+				},
+				grant.WithGrantMetadata(
+					map[string]interface{}{
+						"delegations": byDelegate[delegateId],
+					},
+				),
+			),
+		)
+	}
+	return outputGrants
+}
+
+// Grant is not supported for delegations, which need a date range that
+// cannot be expressed through an entitlement grant.
+func (o *userBuilder) Grant(
 	_ context.Context,
 	_ *v2.Resource,
-	_ *pagination.Token,
+	_ *v2.Entitlement,
 ) (
 	[]*v2.Grant,
-	string,
 	annotations.Annotations,
 	error,
 ) {
-	return nil, "", nil, nil
+	return nil, nil, errors.New("baton-coupa: delegations must be created in Coupa")
 }
 
-func newUserBuilder(ctx context.Context, client *client.Client) *userBuilder {
-	return &userBuilder{
-		client: client,
+// Revoke removes every active or future delegation from the user to the
+// principal.
+func (o *userBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	err := o.guardrails.checkUser(grant.Principal.Id.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	l := ctxzap.Extract(ctx)
+
+	if grant.Principal.Id.ResourceType != userResourceType.Id {
+		return nil, fmt.Errorf("baton-coupa: principal resource type is not %s", userResourceType.Id)
+	}
+
+	now := time.Now()
+	delegationIds := make([]int, 0)
+	lastId := ""
+	for {
+		var target client.DelegationsQueryResponse
+		response, _, err := o.client.Query(
+			ctx,
+			client.DelegateDelegationsQuery(
+				grant.Entitlement.Resource.Id.Resource,
+				grant.Principal.Id.Resource,
+				now,
+				lastId,
+			),
+			&target,
+		)
+		if err != nil {
+			return nil, err
+		}
+		response.Body.Close()
+
+		if len(target.Delegations) == 0 {
+			break
+		}
+		for _, delegation := range target.Delegations {
+			delegationIds = append(delegationIds, delegation.ID)
+		}
+		lastId = strconv.Itoa(target.Delegations[len(target.Delegations)-1].ID)
+	}
+
+	if len(delegationIds) == 0 {
+		l.Info(
+			"baton-coupa: delegation not found for user",
+		)
+
+		return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+	}
+
+	for _, delegationId := range delegationIds {
+		_, err := o.client.DeleteDelegation(ctx, delegationId)
+		if err != nil {
+			l.Error(
+				"baton-coupa: error deleting delegation",
+				zap.Error(err),
+				zap.Int("delegation_id", delegationId),
+			)
+			return nil, err
+		}
+	}
+
+	return nil, nil
+}
+
+func newUserBuilder(
+	ctx context.Context,
+	client *client.Client,
+	syncOffboardingRisk bool,
+	scopedByBusinessEntity bool,
+	guardrails *guardrails,
+) *userBuilder {
+	builder := &userBuilder{
+		client:                 client,
+		guardrails:             guardrails,
+		scopedByBusinessEntity: scopedByBusinessEntity,
+	}
+	if syncOffboardingRisk {
+		builder.documentCounter = newDocumentCounter(client)
 	}
+	return builder
 }
@@ -0,0 +1,33 @@
+package connector
+
+import (
+	"testing"
+
+	"github.com/conductorone/baton-coupa/pkg/connector/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDelegationGrants(t *testing.T) {
+	resource, err := userResource(&client.User{ID: 7, Email: "delegator@example.com"}, nil, nil)
+	require.NoError(t, err)
+
+	grants := delegationGrants(resource, []*client.Delegation{
+		{ID: 1, StartDate: "2026-01-01", EndDate: "2026-01-31", Delegate: client.ResourceId{Id: 9}},
+		{ID: 2, StartDate: "2026-03-01", EndDate: "2026-03-31", Delegate: client.ResourceId{Id: 10}},
+		{ID: 3, StartDate: "2026-06-01", EndDate: "2026-06-30", Delegate: client.ResourceId{Id: 9}},
+	})
+	require.Len(t, grants, 2)
+	require.Equal(t, "9", grants[0].Principal.Id.Resource)
+	require.Equal(t, "10", grants[1].Principal.Id.Resource)
+
+	metadata := &v2.GrantMetadata{}
+	annos := annotations.Annotations(grants[0].Annotations)
+	ok, err := annos.Pick(metadata)
+	require.NoError(t, err)
+	require.True(t, ok)
+	delegations := metadata.Metadata.GetFields()["delegations"].GetListValue().GetValues()
+	require.Len(t, delegations, 2)
+	require.Equal(t, float64(3), delegations[1].GetStructValue().GetFields()["delegation_id"].GetNumberValue())
+}
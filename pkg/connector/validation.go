@@ -0,0 +1,184 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/conductorone/baton-coupa/pkg/connector/client"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"golang.org/x/oauth2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+type ValidationCategory string
+
+const (
+	ValidationCategoryAuth        ValidationCategory = "auth"
+	ValidationCategoryScope       ValidationCategory = "scope"
+	ValidationCategoryNetwork     ValidationCategory = "network"
+	ValidationCategoryInstanceUrl ValidationCategory = "instance_url"
+	ValidationCategoryUnknown     ValidationCategory = "unknown"
+)
+
+type ValidationProblem struct {
+	Check    string             `json:"check"`
+	Category ValidationCategory `json:"category"`
+	Message  string             `json:"message"`
+}
+
+// ValidationReport is the outcome of Validate. Scope problems on a resource
+// type only disable its syncer, every other problem fails the validation.
+type ValidationReport struct {
+	Problems        []ValidationProblem `json:"problems"`
+	DisabledSyncers []string            `json:"disabled_syncers"`
+	blocking        []ValidationProblem
+}
+
+func newValidationReport() *ValidationReport {
+	return &ValidationReport{
+		Problems:        make([]ValidationProblem, 0),
+		DisabledSyncers: make([]string, 0),
+	}
+}
+
+func (r *ValidationReport) add(check string, category ValidationCategory, err error, blocking bool) {
+	problem := ValidationProblem{
+		Check:    check,
+		Category: category,
+		Message:  err.Error(),
+	}
+	r.Problems = append(r.Problems, problem)
+	if blocking {
+		r.blocking = append(r.blocking, problem)
+	}
+}
+
+// Err summarizes the blocking problems by category, or returns nil when there
+// are none. The report is attached to the error as a status detail, since the
+// connector builder drops the annotations of a failed Validate.
+func (r *ValidationReport) Err() error {
+	if len(r.blocking) == 0 {
+		return nil
+	}
+
+	messages := make([]string, 0, len(r.blocking))
+	for _, problem := range r.blocking {
+		messages = append(messages, fmt.Sprintf("%s problem on %s: %s", problem.Category, problem.Check, problem.Message))
+	}
+	st := status.New(codes.FailedPrecondition, fmt.Sprintf("baton-coupa: validation failed: %s", strings.Join(messages, "; ")))
+
+	reportAnnotation, err := r.annotation()
+	if err != nil {
+		return st.Err()
+	}
+	detailed, err := st.WithDetails(reportAnnotation)
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+// annotation returns the report as a struct, so it can be read from the
+// Validate response.
+func (r *ValidationReport) annotation() (*structpb.Struct, error) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]interface{}
+	err = json.Unmarshal(data, &fields)
+	if err != nil {
+		return nil, err
+	}
+
+	return structpb.NewStruct(fields)
+}
+
+// classifyError tells which kind of problem an error from Coupa points at.
+// Only a 403 or an invalid_scope token error are scope problems; errors that
+// carry nothing recognizable, such as GraphQL schema errors, are unknown.
+func classifyError(err error) ValidationCategory {
+	var retrieveError *oauth2.RetrieveError
+	if errors.As(err, &retrieveError) {
+		switch {
+		case retrieveError.ErrorCode == "invalid_scope":
+			return ValidationCategoryScope
+		case retrieveError.Response != nil && retrieveError.Response.StatusCode == http.StatusNotFound:
+			return ValidationCategoryInstanceUrl
+		default:
+			return ValidationCategoryAuth
+		}
+	}
+
+	if errors.Is(err, client.ErrMissingWriteScopes) {
+		return ValidationCategoryScope
+	}
+
+	var dnsError *net.DNSError
+	if errors.As(err, &dnsError) {
+		return ValidationCategoryInstanceUrl
+	}
+
+	if st, ok := status.FromError(err); ok {
+		switch st.Code() {
+		case codes.Unauthenticated:
+			return ValidationCategoryAuth
+		case codes.PermissionDenied:
+			return ValidationCategoryScope
+		case codes.NotFound:
+			return ValidationCategoryInstanceUrl
+		case codes.DeadlineExceeded, codes.Unavailable:
+			return ValidationCategoryNetwork
+		}
+	}
+
+	var urlError *url.Error
+	if errors.As(err, &urlError) {
+		return ValidationCategoryNetwork
+	}
+
+	return ValidationCategoryUnknown
+}
+
+// validate fetches the tokens, probes the GraphQL query of each resource type
+// and a REST read, and reports what failed.
+func (d *Connector) validate(ctx context.Context) (*ValidationReport, error) {
+	report := newValidationReport()
+
+	err := d.client.Initialize(ctx)
+	if err != nil {
+		report.add("token", classifyError(err), err, true)
+		return report, report.Err()
+	}
+
+	d.probeResourceTypes(ctx, report)
+
+	err = d.client.ProbeUsers(ctx)
+	if err != nil {
+		category := classifyError(err)
+		// REST reads are not needed for syncing, only for provisioning.
+		report.add("rest users", category, err, category != ValidationCategoryScope || d.provisioning)
+	}
+
+	return report, report.Err()
+}
+
+func validationAnnotations(report *ValidationReport) (annotations.Annotations, error) {
+	reportAnnotation, err := report.annotation()
+	if err != nil {
+		return nil, err
+	}
+
+	var annos annotations.Annotations
+	annos.Append(reportAnnotation)
+	return annos, nil
+}
@@ -0,0 +1,88 @@
+package connector
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/conductorone/baton-coupa/pkg/connector/client"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestClassifyError(t *testing.T) {
+	testCases := []struct {
+		message  string
+		err      error
+		expected ValidationCategory
+	}{
+		{
+			message:  "invalid client",
+			err:      &oauth2.RetrieveError{Response: &http.Response{StatusCode: http.StatusUnauthorized}, ErrorCode: "invalid_client"},
+			expected: ValidationCategoryAuth,
+		}, {
+			message:  "invalid scope",
+			err:      &oauth2.RetrieveError{Response: &http.Response{StatusCode: http.StatusBadRequest}, ErrorCode: "invalid_scope"},
+			expected: ValidationCategoryScope,
+		}, {
+			message:  "missing write scopes",
+			err:      fmt.Errorf("%w: core.user.write", client.ErrMissingWriteScopes),
+			expected: ValidationCategoryScope,
+		}, {
+			message:  "unknown host",
+			err:      &url.Error{Op: "Post", URL: "https://acme.coupahost.com", Err: &net.DNSError{Err: "no such host", IsNotFound: true}},
+			expected: ValidationCategoryInstanceUrl,
+		}, {
+			message:  "forbidden",
+			err:      errors.Join(status.Error(codes.PermissionDenied, "403 Forbidden")),
+			expected: ValidationCategoryScope,
+		}, {
+			message:  "unavailable",
+			err:      status.Error(codes.Unavailable, "503 Service Unavailable"),
+			expected: ValidationCategoryNetwork,
+		}, {
+			message:  "connection refused",
+			err:      &url.Error{Op: "Post", URL: "https://acme.coupahost.com", Err: errors.New("connection refused")},
+			expected: ValidationCategoryNetwork,
+		}, {
+			message:  "graphql error",
+			err:      errors.New("Field 'pcards' doesn't exist on type 'Query'"),
+			expected: ValidationCategoryUnknown,
+		}, {
+			message:  "unknown status",
+			err:      status.Error(codes.Unknown, "418 I'm a teapot"),
+			expected: ValidationCategoryUnknown,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.message, func(t *testing.T) {
+			require.Equal(t, testCase.expected, classifyError(testCase.err))
+		})
+	}
+}
+
+func TestValidationReportErr(t *testing.T) {
+	report := newValidationReport()
+	require.NoError(t, report.Err())
+
+	report.add("graphql group", ValidationCategoryScope, errors.New("403 Forbidden"), false)
+	require.NoError(t, report.Err())
+
+	report.add("token", ValidationCategoryAuth, errors.New("invalid_client"), true)
+	err := report.Err()
+	require.Error(t, err)
+
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Equal(t, codes.FailedPrecondition, st.Code())
+	require.Len(t, st.Details(), 1)
+	detail, ok := st.Details()[0].(*structpb.Struct)
+	require.True(t, ok)
+	require.Len(t, detail.GetFields()["problems"].GetListValue().GetValues(), 2)
+}
@@ -0,0 +1,305 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/conductorone/baton-coupa/pkg/connector/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	resourceSdk "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+const warehouseAccessEntitlementName = "access"
+
+type warehouseBuilder struct {
+	client     *client.Client
+	guardrails *guardrails
+}
+
+func (o *warehouseBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return warehouseResourceType
+}
+
+func warehouseResource(warehouse *client.Warehouse, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	description := fmt.Sprintf("%s warehouse in Coupa", warehouse.Name)
+	if warehouse.Description != nil && *warehouse.Description != "" {
+		description = *warehouse.Description
+	}
+
+	return resourceSdk.NewResource(
+		warehouse.Name,
+		warehouseResourceType,
+		warehouse.ID,
+		resourceSdk.WithParentResourceID(parentResourceID),
+		resourceSdk.WithDescription(description),
+	)
+}
+
+func (o *warehouseBuilder) List(
+	ctx context.Context,
+	parentResourceID *v2.ResourceId,
+	pToken *pagination.Token,
+) (
+	[]*v2.Resource,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	logger := ctxzap.Extract(ctx)
+	logger.Debug("Starting Warehouses List", zap.String("token", pToken.Token))
+
+	outputResources := make([]*v2.Resource, 0)
+	var outputAnnotations annotations.Annotations
+
+	var target client.WarehousesQueryResponse
+	response, ratelimitData, err := o.client.Query(
+		ctx,
+		client.WarehousesQuery(pToken.Token),
+		&target,
+	)
+	outputAnnotations.WithRateLimiting(ratelimitData)
+	if err != nil {
+		return nil, "", outputAnnotations, err
+	}
+	defer response.Body.Close()
+
+	lastId := ""
+	for _, warehouse := range target.Warehouses {
+		resource, err := warehouseResource(warehouse, parentResourceID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		outputResources = append(outputResources, resource)
+		lastId = strconv.Itoa(warehouse.ID)
+	}
+
+	return outputResources, lastId, outputAnnotations, nil
+}
+
+func (o *warehouseBuilder) Entitlements(
+	_ context.Context,
+	resource *v2.Resource,
+	_ *pagination.Token,
+) (
+	[]*v2.Entitlement,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	return []*v2.Entitlement{
+		entitlement.NewPermissionEntitlement(
+			resource,
+			warehouseAccessEntitlementName,
+			entitlement.WithGrantableTo(userResourceType),
+			entitlement.WithDisplayName(
+				fmt.Sprintf("%s Warehouse Access", resource.DisplayName),
+			),
+			entitlement.WithDescription(
+				fmt.Sprintf("Access to the %s warehouse in Coupa Inventory", resource.DisplayName),
+			),
+		),
+	}, "", nil, nil
+}
+
+func (o *warehouseBuilder) Grants(
+	ctx context.Context,
+	resource *v2.Resource,
+	pToken *pagination.Token,
+) (
+	[]*v2.Grant,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	logger := ctxzap.Extract(ctx)
+
+	warehouseId := resource.Id.Resource
+
+	logger.Debug(
+		"Starting Warehouses Grants",
+		zap.String("warehouse_id", warehouseId),
+		zap.String("token", pToken.Token),
+	)
+
+	outputGrants := make([]*v2.Grant, 0)
+	var outputAnnotations annotations.Annotations
+
+	var target client.WarehouseGrantsQueryResponse
+	response, ratelimitData, err := o.client.Query(
+		ctx,
+		client.WarehouseGrantQuery(warehouseId, pToken.Token),
+		&target,
+	)
+	outputAnnotations.WithRateLimiting(ratelimitData)
+	if err != nil {
+		return nil, "", outputAnnotations, err
+	}
+	defer response.Body.Close()
+
+	lastId := ""
+	for _, user := range target.Users {
+		userId := strconv.Itoa(user.Id)
+		outputGrants = append(
+			outputGrants,
+			grant.NewGrant(
+				resource,
+				warehouseAccessEntitlementName,
+				&v2.ResourceId{
+					ResourceType: userResourceType.Id,
+					Resource:     userId,
+				},
+			),
+		)
+		lastId = userId
+	}
+
+	return outputGrants, lastId, outputAnnotations, nil
+}
+
+func (o *warehouseBuilder) Grant(ctx context.Context, resource *v2.Resource, entitlement *v2.Entitlement) ([]*v2.Grant, annotations.Annotations, error) {
+	err := o.guardrails.checkGrant(entitlement.Resource.Id.ResourceType, entitlement.Resource.Id.Resource, resource.Id.Resource)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if resource.Id.ResourceType != userResourceType.Id {
+		return nil, nil, fmt.Errorf("baton-coupa: principal resource type is not %s", userResourceType.Id)
+	}
+
+	warehouseIdToAdd, err := strconv.Atoi(entitlement.Resource.Id.Resource)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	userId, err := strconv.Atoi(resource.Id.Resource)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	warehouseIds, err := o.getUserWarehouses(ctx, userId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if slices.Contains(warehouseIds, warehouseIdToAdd) {
+		return []*v2.Grant{}, annotations.New(&v2.GrantAlreadyExists{}), nil
+	}
+
+	newWarehouseIds := append(warehouseIds, warehouseIdToAdd)
+
+	userResponse, _, err := o.client.SetUserWarehouses(ctx, userId, newWarehouseIds)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(userResponse.Warehouses) != len(newWarehouseIds) {
+		return nil, nil, errors.New("baton-coupa: warehouse not added to user")
+	}
+
+	newGrant := grant.NewGrant(
+		entitlement.Resource,
+		warehouseAccessEntitlementName,
+		resource.Id,
+	)
+
+	return []*v2.Grant{newGrant}, nil, nil
+}
+
+func (o *warehouseBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	err := o.guardrails.checkUser(grant.Principal.Id.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	l := ctxzap.Extract(ctx)
+
+	if grant.Principal.Id.ResourceType != userResourceType.Id {
+		return nil, fmt.Errorf("baton-coupa: principal resource type is not %s", userResourceType.Id)
+	}
+
+	warehouseIdToRemove, err := strconv.Atoi(grant.Entitlement.Resource.Id.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	userId, err := strconv.Atoi(grant.Principal.Id.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	warehouseIds, err := o.getUserWarehouses(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	index := slices.Index(warehouseIds, warehouseIdToRemove)
+	if index < 0 {
+		l.Info(
+			"baton-coupa: warehouse not found in user",
+		)
+
+		return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+	}
+
+	newWarehouseIds := slices.Delete(warehouseIds, index, index+1)
+
+	userResponse, _, err := o.client.SetUserWarehouses(ctx, userId, newWarehouseIds)
+	if err != nil {
+		l.Error(
+			"baton-coupa: error setting warehouses",
+			zap.Error(err),
+			zap.Ints("warehouses", newWarehouseIds),
+		)
+		return nil, err
+	}
+
+	if len(userResponse.Warehouses) != len(newWarehouseIds) {
+		return nil, errors.New("baton-coupa: warehouse was not removed")
+	}
+
+	return nil, nil
+}
+
+func (o *warehouseBuilder) getUserWarehouses(ctx context.Context, userId int) ([]int, error) {
+	var target client.UserWarehousesResponse
+	response, _, err := o.client.Query(
+		ctx,
+		client.GetUserWarehouses(userId),
+		&target,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if len(target.Users) == 0 {
+		return nil, errors.New("baton-coupa: user not found")
+	}
+
+	if len(target.Users) > 1 {
+		return nil, fmt.Errorf("baton-coupa: multiple users found for id %d", userId)
+	}
+
+	warehouseIds := make([]int, 0)
+	for _, warehouse := range target.Users[0].Warehouses {
+		warehouseIds = append(warehouseIds, warehouse.Id)
+	}
+
+	return warehouseIds, nil
+}
+
+func newWarehouseBuilder(ctx context.Context, client *client.Client, guardrails *guardrails) *warehouseBuilder {
+	return &warehouseBuilder{
+		client:     client,
+		guardrails: guardrails,
+	}
+}